@@ -0,0 +1,195 @@
+package goskiplist
+
+import (
+	"context"
+	"sync"
+)
+
+/*Monoid : Summary of items, such as a count, a sum or a maximum.
+Combine must be associative and Identity, the summary of no item,
+must leave any summary unchanged when combined with it.
+Combine is always called with the lesser items on the left. */
+type Monoid interface {
+	Identity() interface{}
+	Measure(item SkiplistItem) interface{}
+	Combine(a, b interface{}) interface{}
+}
+
+// aggregateItem value of the nodes of an AggregateSkiplist, the link
+// leaving the node on each level also holds the summary of the items
+// it jumps over, up to and including the next node
+type aggregateItem struct {
+	item SkiplistItem
+	agg  []interface{} // per level of the node
+}
+
+/*Less : Order by the items held */
+func (a *aggregateItem) Less(b SkiplistItem) bool {
+	return a.item.Less(b.(*aggregateItem).item)
+}
+
+/*Equals : Equal if the items held are */
+func (a *aggregateItem) Equals(b SkiplistItem) bool {
+	return a.item.Equals(b.(*aggregateItem).item)
+}
+
+/*AggregateSkiplist : Sorted set of items answering range aggregates
+over a Monoid in O(log n) expected. The items are the nodes of a
+Skiplist and every link of it also holds the summary of the items it
+jumps over, so a range is summed up along a single search path.
+Insert and Remove link and unlink through the Skiplist, then rebuild
+the summaries of the links over the node level by level from the bottom.
+They hold the lock while doing so, Aggregate, Contains and Len share it.
+Must be initialised with NewAggregateSkiplist before use. */
+type AggregateSkiplist struct {
+	items  *Skiplist // an *aggregateItem per node
+	monoid Monoid
+	lock   sync.RWMutex // held by writers until the summaries are rebuilt
+}
+
+/*NewAggregateSkiplist : Create an empty AggregateSkiplist summarising
+its items with monoid, other parameters are as for New. */
+func NewAggregateSkiplist(prob float64, maxLevels int, fastRandom bool, monoid Monoid) *AggregateSkiplist {
+
+	list := new(AggregateSkiplist)
+
+	list.items = New(prob, maxLevels, fastRandom)
+	list.monoid = monoid
+
+	head := &aggregateItem{agg: make([]interface{}, SkiplistMaxLevel)}
+	for level := range head.agg {
+		head.agg[level] = monoid.Identity()
+	}
+	list.items.head.value = head
+
+	return list
+}
+
+/*Len : Amount of items stored. Thread safe. */
+func (list *AggregateSkiplist) Len() int {
+	return list.items.Len()
+}
+
+/*Contains : Return true if an item equal to val is stored. Thread safe. */
+func (list *AggregateSkiplist) Contains(val SkiplistItem) bool {
+	defer list.lock.RUnlock()
+	list.lock.RLock()
+
+	return list.items.Contains(&aggregateItem{item: val})
+}
+
+/*Insert : Insert v, updating the summaries of the links jumping over it.
+Returns false if an equal item is already stored. Thread safe. */
+func (list *AggregateSkiplist) Insert(v SkiplistItem) bool {
+	defer list.lock.Unlock()
+	list.lock.Lock()
+
+	var prev, next [SkiplistMaxLevel]*skiplistNode
+	value := &aggregateItem{item: v}
+	if list.items.insert(context.Background(), value, prev[:], next[:], false, 0) != nil {
+		return false
+	}
+
+	node := prev[0].loadNext(0)
+	value.agg = make([]interface{}, node.topLevel+1)
+
+	// bottom up, a summary is built from the level below
+	for level := 0; level < list.items.Height(); level++ {
+		list.summarise(prev[level], level)
+		if level <= node.topLevel {
+			list.summarise(node, level)
+		}
+	}
+
+	return true
+}
+
+/*Remove : Remove the item equal to val, updating the summaries of the
+links that jumped over it. Returns false if there is none. Thread safe. */
+func (list *AggregateSkiplist) Remove(val SkiplistItem) bool {
+	defer list.lock.Unlock()
+	list.lock.Lock()
+
+	var prev, next [SkiplistMaxLevel]*skiplistNode
+	if list.items.remove(context.Background(), &aggregateItem{item: val}, prev[:], next[:], false) != nil {
+		return false
+	}
+
+	levels := list.items.Height()
+	for level := 0; level < levels; level++ {
+		list.summarise(prev[level], level)
+	}
+
+	// shrink if the highest levels are empty
+	list.items.lock.Lock()
+	list.items.shrink()
+	head := list.items.head.value.(*aggregateItem)
+	for level := list.items.nLevels; level < levels; level++ {
+		head.agg[level] = list.monoid.Identity()
+	}
+	list.items.lock.Unlock()
+
+	return true
+}
+
+/*Aggregate : Return the summary of every item x with lo <= x < hi,
+the Identity of the Monoid if there is none. A nil lo or hi leaves that
+side of the range open. O(log n) expected. Thread safe. */
+func (list *AggregateSkiplist) Aggregate(lo, hi SkiplistItem) interface{} {
+	defer list.lock.RUnlock()
+	list.lock.RLock()
+
+	// last node before the range, the links leaving it jump into it
+	head := list.items.head
+	pred := head
+	if lo != nil {
+		var prev, next [SkiplistMaxLevel]*skiplistNode
+		list.items.Find(&aggregateItem{item: lo}, prev[:], next[:])
+		pred = prev[0]
+	}
+
+	// take the highest link not leaving the range, climbing then descending
+	acc := list.monoid.Identity()
+	for {
+		level := pred.topLevel
+		if pred == head {
+			level = list.items.Height() - 1
+		}
+		for level >= 0 && !inside(pred.loadNext(level), hi) {
+			level--
+		}
+		if level < 0 {
+			return acc
+		}
+
+		acc = list.monoid.Combine(acc, pred.value.(*aggregateItem).agg[level])
+		pred = pred.loadNext(level)
+	}
+}
+
+// inside true if node is an item before hi, nil meaning no bound
+func inside(node *skiplistNode, hi SkiplistItem) bool {
+	return node != nil && (hi == nil || node.value.(*aggregateItem).item.Less(hi))
+}
+
+// summarise rebuild the summary of the link leaving node on level
+// from the links of the level below, which must be up to date
+func (list *AggregateSkiplist) summarise(node *skiplistNode, level int) {
+	end := node.loadNext(level)
+	agg := node.value.(*aggregateItem).agg
+
+	if level == 0 {
+		if end == nil {
+			agg[0] = list.monoid.Identity()
+		} else {
+			agg[0] = list.monoid.Measure(end.value.(*aggregateItem).item)
+		}
+		return
+	}
+
+	acc := list.monoid.Identity()
+	for curr := node; curr != end; curr = curr.loadNext(level - 1) {
+		acc = list.monoid.Combine(acc, curr.value.(*aggregateItem).agg[level-1])
+	}
+	agg[level] = acc
+}
@@ -0,0 +1,282 @@
+package goskiplist
+
+import (
+	"fmt"
+	"runtime"
+	"sync/atomic"
+	"unsafe"
+)
+
+/*Batch : Inserts and removes applied to a Skiplist as a single
+atomic step by Apply. The zero value is an empty batch.
+Not threadsafe */
+type Batch struct {
+	items   []SkiplistItem
+	removes []bool
+}
+
+/*Insert : Add an insert of v to the batch */
+func (batch *Batch) Insert(v SkiplistItem) {
+	batch.items = append(batch.items, v)
+	batch.removes = append(batch.removes, false)
+}
+
+/*Remove : Add a remove of val to the batch */
+func (batch *Batch) Remove(val SkiplistItem) {
+	batch.items = append(batch.items, val)
+	batch.removes = append(batch.removes, true)
+}
+
+/*Len : Amount of operations in the batch */
+func (batch *Batch) Len() int {
+	return len(batch.items)
+}
+
+// batchTxn batch being applied, its pending versions
+// become visible at once when commit is set
+type batchTxn struct {
+	commit int64
+}
+
+// batchStage operation of a batch claimed on its node
+type batchStage struct {
+	node    *skiplistNode
+	item    SkiplistItem // inserted or removed item
+	remove  bool
+	created bool // node linked by the batch
+}
+
+/*Apply : Apply every operation of batch to the Skiplist atomically.
+Every insert must be of an item not in the Skiplist and every remove
+of an item in it, else nothing is applied and an error wrapping
+ErrExists or ErrNotFound describes the first operation in item order
+that failed. An item may appear only once.
+
+Concurrent readers and snapshots see either none or all of the batch.
+Items are claimed in ascending order, so batches sharing items cannot
+deadlock, other writers of a claimed item wait for the batch.
+Thread safe. */
+func (list *Skiplist) Apply(batch *Batch) error {
+
+	items := batch.items
+	order := sortedOrder(items)
+	for index := 1; index < len(order); index++ {
+		if items[order[index]].Equals(items[order[index-1]]) {
+			return fmt.Errorf("batch: item %v appears more than once", items[order[index]])
+		}
+	}
+
+	if len(order) == 0 {
+		return nil
+	}
+
+	txn := new(batchTxn)
+	expires := list.defaultExpiry()
+
+	// buffers to store prev and next pointers,
+	// kept between items as a finger
+	prev := make([]*skiplistNode, SkiplistMaxLevel)
+	next := make([]*skiplistNode, SkiplistMaxLevel)
+
+	stages := make([]batchStage, 0, len(order))
+	for _, index := range order {
+		stage, err := list.stage(txn, items[index], batch.removes[index], expires, prev, next, len(stages) > 0)
+		if err != nil {
+			list.rollback(stages)
+			return err
+		}
+		stages = append(stages, stage)
+	}
+
+	list.commitBatch(txn, stages)
+	return nil
+}
+
+// stage claim the node of item for txn and add a pending version to it,
+// linking a new node if there is none
+func (list *Skiplist) stage(txn *batchTxn, item SkiplistItem, remove bool, expires int64,
+	prev, next []*skiplistNode, finger bool) (batchStage, error) {
+
+	// drawn once a node has to be created
+	topLevel := 0
+
+	for {
+		foundLevel := list.search(item, prev, next, finger)
+
+		if foundLevel == -1 {
+			if remove {
+				return batchStage{}, fmt.Errorf("batch: %w: %v", ErrNotFound, item)
+			}
+
+			// search again, the list may have grown
+			if topLevel == 0 {
+				topLevel = list.randomLevel()
+				continue
+			}
+
+			// absent until the batch commits
+			newNode := new(skiplistNode)
+			newNode.value = item
+			newNode.topLevel = topLevel - 1
+			newNode.storeOwner(txn)
+			newNode.storeVersion(&nodeVersion{value: item, expires: expires, txn: txn,
+				older: &nodeVersion{value: item, deleted: true}})
+
+			if !list.link(newNode, prev, next) {
+				continue
+			}
+			newNode.storeFullyLinked(true)
+			newNode.mux.Unlock()
+
+			return batchStage{node: newNode, item: item, created: true}, nil
+		}
+
+		node := next[foundLevel]
+		// being unlinked, try again
+		if node.loadMarked() {
+			continue
+		}
+		// wait until stable
+		for !node.loadFullyLinked() {
+		}
+
+		node.mux.Lock()
+		if node.loadMarked() {
+			node.mux.Unlock()
+			continue
+		}
+		// claimed by an other batch, wait for it
+		if node.loadOwner() != nil {
+			node.mux.Unlock()
+			list.await(node)
+			continue
+		}
+
+		live := !node.tombstoned() && !list.expired(node)
+		if live && !remove {
+			node.mux.Unlock()
+			return batchStage{}, fmt.Errorf("batch: %w: %v", ErrExists, item)
+		}
+		if !live && remove {
+			node.mux.Unlock()
+			return batchStage{}, fmt.Errorf("batch: %w: %v", ErrNotFound, item)
+		}
+
+		node.versioned()
+		node.storeOwner(txn)
+		if remove {
+			item = node.item()
+			node.storeVersion(&nodeVersion{value: item, deleted: true, txn: txn, older: node.loadVersion()})
+		} else {
+			node.storeVersion(&nodeVersion{value: item, expires: expires, txn: txn, older: node.loadVersion()})
+		}
+		node.mux.Unlock()
+
+		return batchStage{node: node, item: item, remove: remove}, nil
+	}
+}
+
+// commitBatch make every staged version visible at once,
+// then release the nodes
+func (list *Skiplist) commitBatch(txn *batchTxn, stages []batchStage) {
+
+	// no snapshot can be taken in between
+	list.snapMux.RLock()
+	commit := list.commit()
+	atomic.StoreInt64(&txn.commit, commit)
+	list.snapMux.RUnlock()
+
+	// the nodes are still claimed, no other change of
+	// their items can be queued before these
+	for _, stage := range stages {
+		if stage.remove {
+			list.publish(EventRemove, stage.item)
+		} else {
+			list.publish(EventInsert, stage.item)
+		}
+	}
+
+	delta := 0
+	for _, stage := range stages {
+		node := stage.node
+
+		node.mux.Lock()
+		// committed copy, readers still holding the pending
+		// version see the commit of txn
+		version := *node.loadVersion()
+		version.commit = commit
+		version.txn = nil
+
+		unlink := false
+		if stage.remove {
+			delta--
+
+			// kept as a tombstone only if a snapshot may see it
+			list.snapMux.RLock()
+			unlink = !list.seen(node)
+			list.snapMux.RUnlock()
+			node.storeMarked(unlink)
+		} else {
+			delta++
+
+			atomic.StoreInt64(&node.expires, version.expires)
+			// nothing before the batch
+			if stage.created {
+				version.older = nil
+			}
+		}
+		node.storeVersion(&version)
+		node.storeOwner(nil)
+		node.mux.Unlock()
+
+		// unlinked right away, a remover waiting for it
+		// may hold a lock needed further on
+		if unlink {
+			list.unlinkMarked([]*skiplistNode{node})
+		}
+	}
+
+	// update element count
+	list.lock.Lock()
+	list.nElements += delta
+	list.lock.Unlock()
+
+	list.dispatch()
+	list.enforceCapacity()
+}
+
+// rollback drop the pending versions of stages and
+// unlink the nodes created for them
+func (list *Skiplist) rollback(stages []batchStage) {
+	for _, stage := range stages {
+		node := stage.node
+
+		node.mux.Lock()
+		node.storeVersion(node.loadVersion().older)
+		node.storeOwner(nil)
+		node.storeMarked(stage.created)
+		node.mux.Unlock()
+
+		if stage.created {
+			list.unlinkMarked([]*skiplistNode{node})
+		}
+	}
+}
+
+// loadOwner batch claiming the node, nil if none
+func (node *skiplistNode) loadOwner() *batchTxn {
+	return (*batchTxn)(atomic.LoadPointer(&node.owner))
+}
+
+// storeOwner claim the node for txn, or release it if nil,
+// called with the node locked or before it is linked
+func (node *skiplistNode) storeOwner(txn *batchTxn) {
+	atomic.StorePointer(&node.owner, unsafe.Pointer(txn))
+}
+
+// await wait until the batch claiming node releases it
+func (list *Skiplist) await(node *skiplistNode) {
+	for node.loadOwner() != nil {
+		runtime.Gosched()
+	}
+}
@@ -0,0 +1,100 @@
+package goskiplist
+
+import (
+	"fmt"
+)
+
+// sliceIterator iterates over a slice of items
+type sliceIterator struct {
+	items []SkiplistItem
+	index int
+}
+
+func (it *sliceIterator) Next() bool {
+	if it.index >= len(it.items) {
+		return false
+	}
+	it.index++
+	return true
+}
+
+func (it *sliceIterator) Item() SkiplistItem {
+	return it.items[it.index-1]
+}
+
+/*FromSorted : Replace the contents of the Skiplist with items, which must be
+sorted in ascending order and hold no duplicates.
+The Skiplist parameters define the structure of the new Skiplist,
+levels are generated for every item.
+Returns an error and leaves the Skiplist untouched on unsorted or duplicate input.
+O(N),Not threadsafe */
+func (list *Skiplist) FromSorted(items []SkiplistItem) (*Skiplist, error) {
+	return list.FromIterator(&sliceIterator{items: items})
+}
+
+/*FromIterator : Replace the contents of the Skiplist with the items of it,
+which must be sorted in ascending order and hold no duplicates.
+The Skiplist parameters define the structure of the new Skiplist,
+levels are generated for every item and the default time to live applies.
+Returns an error and leaves the Skiplist untouched on unsorted or duplicate input.
+O(N),Not threadsafe */
+func (list *Skiplist) FromIterator(it Iterator) (*Skiplist, error) {
+
+	head := new(skiplistNode)
+	head.storeFullyLinked(true)
+
+	nLevels := 1
+	nElements := 0
+
+	// keep last node added in each level
+	var prevs [SkiplistMaxLevel]*skiplistNode
+	for level := range prevs {
+		prevs[level] = head
+	}
+
+	expires := list.defaultExpiry()
+
+	var prevElem SkiplistItem
+	for index := 0; it.Next(); index++ {
+		item := it.Item()
+
+		if item == nil {
+			return list, fmt.Errorf("bulk load: nil item at position %d", index)
+		}
+
+		if prevElem != nil {
+			if item.Equals(prevElem) {
+				return list, fmt.Errorf("bulk load: duplicate item %v at position %d", item, index)
+			}
+			if !prevElem.Less(item) {
+				return list, fmt.Errorf("bulk load: item %v at position %d is less than its predecessor %v", item, index, prevElem)
+			}
+		}
+		prevElem = item
+
+		newNode := new(skiplistNode)
+		newNode.value = item
+		newNode.storeFullyLinked(true)
+		newNode.topLevel = coinTosses(list.prob, list.maxLevels, list.fastRandom) - 1
+		newNode.storePrev(prevs[0])
+		newNode.expires = expires
+
+		nLevels = max(newNode.topLevel+1, nLevels)
+
+		for level := newNode.topLevel; level >= 0; level-- {
+			prevs[level].storeNext(level, newNode)
+			prevs[level] = newNode
+		}
+
+		nElements++
+	}
+
+	// input accepted, swap contents
+	list.head = head
+	list.storeTail(prevs[0])
+	list.nLevels = nLevels
+	list.nElements = nElements
+	list.moved()
+
+	return list, nil
+}
@@ -0,0 +1,88 @@
+package goskiplist
+
+/*EvictionPolicy : Remove an item from a Skiplist which grew over capacity
+and return it, nil if nothing can be evicted.
+A policy must not insert to the list it evicts from. */
+type EvictionPolicy func(list *Skiplist) SkiplistItem
+
+// EvictMin evict the smallest item, default policy
+var EvictMin EvictionPolicy = (*Skiplist).PopMin
+
+// EvictMax evict the largest item
+var EvictMax EvictionPolicy = (*Skiplist).PopMax
+
+/*SetCapacity : Set the maximum amount of items and the maximum estimated
+size in bytes of the Skiplist, as reported by Stats. An insert growing the
+list over either limit evicts items until it fits again. Zero disables a limit.
+Threadsafe */
+func (list *Skiplist) SetCapacity(maxElements int, maxBytes uintptr) {
+	list.lock.Lock()
+	list.maxElements = maxElements
+	list.maxBytes = maxBytes
+	list.lock.Unlock()
+
+	list.enforceCapacity()
+}
+
+/*SetEvictionPolicy : Set the policy choosing which items to evict,
+nil restores EvictMin.
+Threadsafe */
+func (list *Skiplist) SetEvictionPolicy(policy EvictionPolicy) {
+	defer list.lock.Unlock()
+	list.lock.Lock()
+	list.evict = policy
+}
+
+/*OnEvict : Set a callback called with every evicted item,
+nil removes the callback.
+Threadsafe */
+func (list *Skiplist) OnEvict(callback func(SkiplistItem)) {
+	defer list.lock.Unlock()
+	list.lock.Lock()
+	list.onEvict = callback
+}
+
+// overCapacity true if the list holds more items
+// or bytes than allowed, also returns the policy to apply
+func (list *Skiplist) overCapacity() (bool, EvictionPolicy, func(SkiplistItem)) {
+	defer list.lock.RUnlock()
+	list.lock.RLock()
+
+	over := (list.maxElements > 0 && list.nElements > list.maxElements) ||
+		(list.maxBytes > 0 && estimatedBytes(list.nElements) > list.maxBytes)
+
+	policy := list.evict
+	if policy == nil {
+		policy = EvictMin
+	}
+
+	return over, policy, list.onEvict
+}
+
+// enforceCapacity evict items until the list fits its capacity,
+// one routine at a time so concurrent inserts do not evict twice
+func (list *Skiplist) enforceCapacity() {
+	over, _, _ := list.overCapacity()
+	if !over {
+		return
+	}
+
+	defer list.evictMux.Unlock()
+	list.evictMux.Lock()
+
+	for {
+		over, policy, callback := list.overCapacity()
+		if !over {
+			return
+		}
+
+		item := policy(list)
+		if item == nil {
+			return
+		}
+
+		if callback != nil {
+			callback(item)
+		}
+	}
+}
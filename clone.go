@@ -0,0 +1,51 @@
+package goskiplist
+
+/*Clone : Copy the Skiplist in O(n), keeping the top level of every node,
+the height, the parameters and the expiration and capacity settings.
+The copy is taken from a snapshot, so it holds the items present at a
+single point in time even while the Skiplist is changed concurrently.
+Subscriptions are not copied. Thread safe. */
+func (list *Skiplist) Clone() *Skiplist {
+
+	clone := list.sibling()
+
+	snap := list.Snapshot()
+	defer snap.Release()
+
+	// the height may exceed the tallest node,
+	// failed inserts raise it as well
+	clone.nLevels = snap.nLevels
+
+	// keep last node added in each level
+	var prevs [SkiplistMaxLevel]*skiplistNode
+	for level := range prevs {
+		prevs[level] = clone.head
+	}
+
+	for node := snap.head.loadNext(0); node != nil; node = node.loadNext(0) {
+		item, expires := snap.visibleExpiry(node)
+		if item == nil {
+			continue
+		}
+
+		newNode := new(skiplistNode)
+		newNode.value = item
+		newNode.storeFullyLinked(true)
+		newNode.topLevel = node.topLevel
+		newNode.expires = expires
+		newNode.storePrev(prevs[0])
+		for level := newNode.topLevel; level >= 0; level-- {
+			prevs[level].storeNext(level, newNode)
+			prevs[level] = newNode
+		}
+
+		clone.nLevels = max(clone.nLevels, newNode.topLevel+1)
+		clone.nElements++
+	}
+
+	// levels may have been lowered since the tallest nodes were inserted
+	clone.maxLevels = max(clone.maxLevels, clone.nLevels)
+
+	clone.storeTail(prevs[0])
+	return clone
+}
@@ -0,0 +1,209 @@
+package goskiplist
+
+import (
+	"fmt"
+)
+
+/*ComputeOp : What Compute does with the item returned by its callback */
+type ComputeOp int
+
+const (
+	// ComputeKeep leave the Skiplist as it is
+	ComputeKeep ComputeOp = iota
+	// ComputeSet insert the returned item or replace the existing one with it
+	ComputeSet
+	// ComputeDelete remove the existing item
+	ComputeDelete
+)
+
+/*Compute : Atomically read, modify and write the item equal to key.
+fn is called with the current item and whether it exists, while holding
+the lock of its node, so no other writer of key can interleave.
+The op returned decides whether the item is kept, set to the returned
+item, which must equal key, or deleted. fn must not call into the
+Skiplist. If fn panics nothing is changed and the panic is passed on,
+setting an item not equal to key panics.
+Returns the item present afterwards and whether there is one.
+Thread safe. */
+func (list *Skiplist) Compute(key SkiplistItem, fn func(old SkiplistItem, exists bool) (SkiplistItem, ComputeOp)) (SkiplistItem, bool) {
+	// buffers to store prev and next pointers
+	var prev, next [SkiplistMaxLevel]*skiplistNode
+
+	// events are delivered once every lock is released
+	defer list.dispatch()
+
+	// drawn once a node has to be created
+	topLevel := 0
+
+	for {
+		foundLevel := list.search(key, prev[:], next[:], false)
+
+		if foundLevel == -1 {
+			// search again, the list may have grown
+			if topLevel == 0 {
+				topLevel = list.randomLevel()
+				continue
+			}
+
+			// absent placeholder, claimed so other
+			// writers of key wait for the callback
+			newNode := new(skiplistNode)
+			newNode.value = key
+			newNode.topLevel = topLevel - 1
+			newNode.storeOwner(new(batchTxn))
+			newNode.storeVersion(&nodeVersion{value: key, deleted: true})
+
+			if !list.link(newNode, prev[:], next[:]) {
+				continue
+			}
+			newNode.storeFullyLinked(true)
+
+			return list.computeCreated(newNode, key, fn)
+		}
+
+		node := next[foundLevel]
+		// being unlinked, try again
+		if node.loadMarked() {
+			continue
+		}
+		// wait until stable
+		for !node.loadFullyLinked() {
+		}
+
+		node.mux.Lock()
+		if node.loadMarked() {
+			node.mux.Unlock()
+			continue
+		}
+		// part of a pending batch, wait for it
+		if node.loadOwner() != nil {
+			node.mux.Unlock()
+			list.await(node)
+			continue
+		}
+		// expired, remove it and try again
+		if !node.tombstoned() && list.expired(node) {
+			node.mux.Unlock()
+			list.expire(node)
+			continue
+		}
+
+		return list.computeFound(node, key, fn)
+	}
+}
+
+// computeFound run fn on a locked node found for key and unlock it
+func (list *Skiplist) computeFound(node *skiplistNode, key SkiplistItem,
+	fn func(SkiplistItem, bool) (SkiplistItem, ComputeOp)) (SkiplistItem, bool) {
+
+	exists := !node.tombstoned()
+	var old SkiplistItem
+	if exists {
+		old = node.item()
+	}
+
+	// fn panicked, the node is left as it was
+	called := false
+	defer func() {
+		if !called {
+			node.mux.Unlock()
+		}
+	}()
+
+	item, op := fn(old, exists)
+	called = true
+
+	switch {
+	case op == ComputeSet:
+		if !computed(key, item) {
+			node.mux.Unlock()
+			panic(fmt.Sprintf("compute: item %v does not equal key %v", item, key))
+		}
+
+		node.versioned()
+		if exists {
+			list.commitVersion(node, item, node.current().expires, EventUpdate)
+			node.mux.Unlock()
+			return item, true
+		}
+
+		// kept for snapshots, insert a new version
+		list.commitVersion(node, item, list.defaultExpiry(), EventInsert)
+		node.mux.Unlock()
+
+		list.lock.Lock()
+		list.nElements++
+		list.lock.Unlock()
+
+		list.enforceCapacity()
+		return item, true
+
+	case op == ComputeDelete && exists:
+		unlink := list.retire(node)
+		node.mux.Unlock()
+
+		if unlink {
+			list.unlinkMarked([]*skiplistNode{node})
+		}
+
+		list.lock.Lock()
+		list.nElements--
+		list.lock.Unlock()
+
+		return nil, false
+	}
+
+	node.mux.Unlock()
+	return old, exists
+}
+
+// computeCreated run fn on a placeholder node linked for key,
+// which becomes the item or is unlinked again
+func (list *Skiplist) computeCreated(node *skiplistNode, key SkiplistItem,
+	fn func(SkiplistItem, bool) (SkiplistItem, ComputeOp)) (SkiplistItem, bool) {
+
+	// fn panicked, unlink the placeholder so writers
+	// waiting for it go on
+	called := false
+	defer func() {
+		if !called {
+			node.storeMarked(true)
+			node.storeOwner(nil)
+			node.mux.Unlock()
+			list.unlinkMarked([]*skiplistNode{node})
+		}
+	}()
+
+	item, op := fn(nil, false)
+	called = true
+
+	if op == ComputeSet && computed(key, item) {
+		list.commitVersion(node, item, list.defaultExpiry(), EventInsert)
+		node.storeOwner(nil)
+		node.mux.Unlock()
+
+		list.lock.Lock()
+		list.nElements++
+		list.lock.Unlock()
+
+		list.enforceCapacity()
+		return item, true
+	}
+
+	node.storeMarked(true)
+	node.storeOwner(nil)
+	node.mux.Unlock()
+
+	list.unlinkMarked([]*skiplistNode{node})
+
+	if op == ComputeSet {
+		panic(fmt.Sprintf("compute: item %v does not equal key %v", item, key))
+	}
+	return nil, false
+}
+
+// computed true if the item set by a Compute callback equals its key,
+// anything else would break the order of the Skiplist
+func computed(key, item SkiplistItem) bool {
+	return item != nil && item.Equals(key)
+}
@@ -0,0 +1,28 @@
+package goskiplist
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// errors reported by the Try variants of Insert and Remove,
+// compare with errors.Is
+var (
+	// ErrExists an item equal to the inserted one is already in the Skiplist
+	ErrExists = errors.New("skiplist: item already exists")
+	// ErrNotFound no item equal to the removed one is in the Skiplist
+	ErrNotFound = errors.New("skiplist: item not found")
+	// ErrConcurrentRemoval the item was found but an other routine removed it first
+	ErrConcurrentRemoval = errors.New("skiplist: item removed concurrently")
+	// ErrCancelled the context was done before the operation took effect
+	ErrCancelled = errors.New("skiplist: operation cancelled")
+)
+
+// cancelled ErrCancelled wrapping the reason if ctx is done, else nil
+func cancelled(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("%w: %v", ErrCancelled, err)
+	}
+	return nil
+}
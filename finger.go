@@ -0,0 +1,78 @@
+package goskiplist
+
+import (
+	"context"
+	"sync/atomic"
+)
+
+/*Finger : Remembered search position in a Skiplist. Lookups through it
+start from the position of the previous one and climb only as high as
+needed, O(log d) expected for a distance d from the last key, so scans
+with locality avoid the full search from the head.
+Nodes removed underneath it send the next lookup back to the head.
+Not threadsafe, use one per routine. The Skiplist itself can be
+changed concurrently. */
+type Finger struct {
+	list       *Skiplist
+	generation int64 // generation of the list the position belongs to, -1 if none
+	prev, next [SkiplistMaxLevel]*skiplistNode
+}
+
+/*Finger : Return a finger positioned at the head of the Skiplist.
+Thread safe. */
+func (list *Skiplist) Finger() *Finger {
+	return &Finger{list: list, generation: -1}
+}
+
+/*Contains : Return true if an item equal to val exists in the Skiplist,
+searching from the finger. */
+func (finger *Finger) Contains(val SkiplistItem) bool {
+	return finger.Get(val) != nil
+}
+
+/*Get : Get the item equal to val, nil if there is none,
+searching from the finger. */
+func (finger *Finger) Get(val SkiplistItem) SkiplistItem {
+	list := finger.list
+
+	foundLevel := list.search(val, finger.prev[:], finger.next[:], finger.positioned())
+	if foundLevel != -1 && list.isLive(finger.next[foundLevel]) {
+		return finger.next[foundLevel].item()
+	}
+	return nil
+}
+
+/*Insert : Insert v as by Skiplist.Insert, searching from the finger. */
+func (finger *Finger) Insert(v SkiplistItem) bool {
+	list := finger.list
+
+	return list.insert(context.Background(), v, finger.prev[:], finger.next[:], finger.positioned(), list.defaultExpiry()) == nil
+}
+
+/*Remove : Remove val as by Skiplist.Remove, searching from the finger. */
+func (finger *Finger) Remove(val SkiplistItem) bool {
+	list := finger.list
+
+	return list.remove(context.Background(), val, finger.prev[:], finger.next[:], finger.positioned()) == nil
+}
+
+// positioned true if the finger holds the position of an earlier search,
+// false the first time or once nodes were moved between lists, as by
+// FromSorted, Split or Join, since the position may now be in an other one
+func (finger *Finger) positioned() bool {
+	generation := atomic.LoadInt64(&finger.list.generation)
+	if finger.generation != generation {
+		finger.generation = generation
+		return false
+	}
+	return true
+}
+
+// seek first live node not less than val, nil if none,
+// searching from the finger
+func (finger *Finger) seek(val SkiplistItem) *skiplistNode {
+	list := finger.list
+
+	list.search(val, finger.prev[:], finger.next[:], finger.positioned())
+	return list.nextLive(finger.next[0])
+}
@@ -1,3 +1,3 @@
 module github.com/gerrish/goskiplist
 
-go 1.10
\ No newline at end of file
+go 1.13
\ No newline at end of file
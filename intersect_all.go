@@ -0,0 +1,151 @@
+package goskiplist
+
+import (
+	"sort"
+)
+
+/*MultiIntersectIterator : Lazy intersection of many Skiplists, as for
+posting lists. Lists are visited smallest first, each in turn jumping
+from its finger to the current candidate, and a list landing past the
+candidate makes its item the new one, so long runs of items missing
+from some list are skipped at the cost of a single search.
+Items changed concurrently may or may not be seen. */
+type MultiIntersectIterator struct {
+	cursors []intersectCursor // by ascending list size
+	item    SkiplistItem
+	started bool
+}
+
+// intersectCursor position in one of the intersected lists
+type intersectCursor struct {
+	finger *Finger
+	node   *skiplistNode
+	item   SkiplistItem // item of node, nil once exhausted
+}
+
+func (cursor *intersectCursor) seek(val SkiplistItem) {
+	cursor.set(cursor.finger.seek(val))
+}
+
+func (cursor *intersectCursor) next() {
+	cursor.set(cursor.finger.list.nextLive(cursor.node.loadNext(0)))
+}
+
+func (cursor *intersectCursor) set(node *skiplistNode) {
+	cursor.node = node
+	cursor.item = nil
+	if node != nil {
+		cursor.item = node.item()
+	}
+}
+
+/*IntersectAll : Intersect every one of lists into a new Skiplist with
+the parameters of the smallest of them, as IntersectAllIterator streams it.
+Items get the default time to live of the new Skiplist.
+Thread safe, concurrent changes may or may not be seen. */
+func IntersectAll(lists ...*Skiplist) *Skiplist {
+	it := IntersectAllIterator(lists...)
+	if len(it.cursors) == 0 {
+		return New(0.5, SkiplistMaxLevel, FAST)
+	}
+
+	intersected := it.cursors[0].finger.list.sibling()
+	// ascending and without duplicates, cannot fail
+	intersected.FromIterator(it)
+	return intersected
+}
+
+/*IntersectAllIterator : Return an iterator over the items present in
+every one of lists. Thread safe. */
+func IntersectAllIterator(lists ...*Skiplist) *MultiIntersectIterator {
+	ordered := make([]*Skiplist, len(lists))
+	copy(ordered, lists)
+
+	// smallest first, its items are the first candidates
+	sizes := make(map[*Skiplist]int, len(lists))
+	for _, list := range lists {
+		sizes[list] = list.Len()
+	}
+	sort.SliceStable(ordered, func(i, j int) bool {
+		return sizes[ordered[i]] < sizes[ordered[j]]
+	})
+
+	cursors := make([]intersectCursor, len(ordered))
+	for index, list := range ordered {
+		cursors[index].finger = list.Finger()
+	}
+
+	return &MultiIntersectIterator{cursors: cursors}
+}
+
+// Next advance to the next item, false when exhausted
+func (it *MultiIntersectIterator) Next() bool {
+	if len(it.cursors) == 0 {
+		return false
+	}
+
+	first := &it.cursors[0]
+	switch {
+	case !it.started:
+		it.started = true
+		first.set(first.finger.list.nextLive(first.finger.list.head.loadNext(0)))
+	case it.item == nil:
+		// exhausted, stay exhausted
+		return false
+	default:
+		first.next()
+	}
+
+	return it.settle()
+}
+
+// Seek move to the first item not less than val, false if there is none
+func (it *MultiIntersectIterator) Seek(val SkiplistItem) bool {
+	if len(it.cursors) == 0 {
+		return false
+	}
+
+	it.started = true
+	it.cursors[0].seek(val)
+	return it.settle()
+}
+
+// Item current item, nil before the first call to Next
+// or once exhausted
+func (it *MultiIntersectIterator) Item() SkiplistItem {
+	return it.item
+}
+
+// settle take the item of the first cursor as candidate and visit the
+// lists round robin until all of them hold it
+func (it *MultiIntersectIterator) settle() bool {
+	cursors := it.cursors
+
+	it.item = nil
+	candidate := cursors[0].item
+	if candidate == nil {
+		return false
+	}
+
+	// lists known to hold the candidate, the one it came from included
+	matched := 1
+	for index := 1 % len(cursors); matched < len(cursors); index = (index + 1) % len(cursors) {
+		cursor := &cursors[index]
+		cursor.seek(candidate)
+
+		switch {
+		case cursor.item == nil:
+			return false
+		case cursor.item.Equals(candidate):
+			matched++
+		default:
+			// landed past it, a new candidate
+			candidate = cursor.item
+			matched = 1
+		}
+	}
+
+	// every cursor is on the candidate
+	it.item = cursors[0].item
+	return true
+}
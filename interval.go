@@ -0,0 +1,737 @@
+package goskiplist
+
+import (
+	"sort"
+	"sync"
+	"sync/atomic"
+	"unsafe"
+)
+
+/*Interval : Half open range [Lo, Hi) of Skiplist items */
+type Interval struct {
+	Lo, Hi SkiplistItem
+}
+
+/*Contains : Return true if Lo <= point < Hi */
+func (iv Interval) Contains(point SkiplistItem) bool {
+	return !point.Less(iv.Lo) && point.Less(iv.Hi)
+}
+
+/*Overlaps : Return true if the interval shares a point with [lo, hi) */
+func (iv Interval) Overlaps(lo, hi SkiplistItem) bool {
+	return iv.Lo.Less(hi) && lo.Less(iv.Hi)
+}
+
+// endpoint value of the nodes of an IntervalSkiplist, key is the end of
+// at least one interval. The edge leaving the node on a level covers
+// [key, next.key) and carries a marker for every interval containing
+// that edge but not the edge above it, so the marked edges of an
+// interval partition it. Changed under the lock of its node only
+type endpoint struct {
+	key      SkiplistItem
+	markers  []unsafe.Pointer // *[]*intervalEntry per level, never changed once stored
+	starting unsafe.Pointer   // *[]*intervalEntry with Lo equal to key, never changed once stored
+	refs     int              // intervals with an endpoint equal to key
+	stamp    uint32           // odd while the links or markers of the node change
+}
+
+/*Less : Order endpoints by key */
+func (point *endpoint) Less(b SkiplistItem) bool {
+	return point.key.Less(b.(*endpoint).key)
+}
+
+/*Equals : Endpoints are equal if their keys are */
+func (point *endpoint) Equals(b SkiplistItem) bool {
+	return point.key.Equals(b.(*endpoint).key)
+}
+
+// intervalEntry stored interval
+type intervalEntry struct {
+	interval Interval
+}
+
+// intervalEdge edge leaving node on level
+type intervalEdge struct {
+	node  *skiplistNode
+	level int
+}
+
+/*IntervalSkiplist : Set of intervals answering stabbing and overlap
+queries, after Hanson's interval skip list. The endpoints are the nodes
+of a Skiplist and every interval marks the fewest edges covering it,
+so a query collects the markers along a single search path.
+As in Skiplist writers lock only the nodes whose edges they change, in
+descending order, and readers do not lock. A reader checks that none of
+the nodes it read changed meanwhile and reads again otherwise, so Stab
+and Overlap return the intervals stored at a single moment.
+Must be initialised with NewIntervalSkiplist before use. */
+type IntervalSkiplist struct {
+	points     *Skiplist // an *endpoint per node, the head holds one without key
+	nIntervals int
+	lock       sync.RWMutex // guards nIntervals
+}
+
+/*NewIntervalSkiplist : Create an empty IntervalSkiplist,
+parameters are as for New. */
+func NewIntervalSkiplist(prob float64, maxLevels int, fastRandom bool) *IntervalSkiplist {
+
+	list := new(IntervalSkiplist)
+
+	list.points = New(prob, maxLevels, fastRandom)
+	list.points.head.value = &endpoint{markers: make([]unsafe.Pointer, SkiplistMaxLevel)}
+
+	return list
+}
+
+/*Len : Amount of intervals stored. Thread safe. */
+func (list *IntervalSkiplist) Len() int {
+	defer list.lock.RUnlock()
+	list.lock.RLock()
+	return list.nIntervals
+}
+
+/*Insert : Insert iv, which must not be empty. Returns false if it is
+empty or an interval with the same endpoints is already stored.
+Thread safe. */
+func (list *IntervalSkiplist) Insert(iv Interval) bool {
+	if iv.Lo == nil || iv.Hi == nil || !iv.Lo.Less(iv.Hi) {
+		return false
+	}
+
+	lo := list.acquire(iv.Lo)
+	hi := list.acquire(iv.Hi)
+
+	if !list.place(&intervalEntry{interval: iv}, lo) {
+		list.release(lo)
+		list.release(hi)
+		return false
+	}
+
+	list.lock.Lock()
+	list.nIntervals++
+	list.lock.Unlock()
+
+	return true
+}
+
+/*Remove : Remove the interval with the same endpoints as iv.
+Returns false if there is none. Thread safe. */
+func (list *IntervalSkiplist) Remove(iv Interval) bool {
+	if iv.Lo == nil || iv.Hi == nil {
+		return false
+	}
+
+	var prev, next [SkiplistMaxLevel]*skiplistNode
+	for {
+		foundLevel := list.points.Find(&endpoint{key: iv.Lo}, prev[:], next[:])
+		if foundLevel == -1 {
+			return false
+		}
+		lo := next[foundLevel]
+		entry := pointOf(lo).lookup(iv.Hi)
+		if entry == nil {
+			return false
+		}
+
+		// moved by a concurrent writer, try again
+		edges := markedEdges(lo, entry)
+		if edges == nil {
+			continue
+		}
+
+		nodes := edgeNodes(edges)
+		lockNodes(nodes)
+		if !sameEdges(edges, markedEdges(lo, entry)) || pointOf(lo).lookup(iv.Hi) != entry {
+			unlockNodes(nodes)
+			continue
+		}
+
+		stamp(nodes)
+		for _, edge := range edges {
+			unmark(edge, entry)
+		}
+		pointOf(lo).storeStarting(without(pointOf(lo).loadStarting(), entry))
+		stamp(nodes)
+
+		last := edges[len(edges)-1]
+		hi := last.node.loadNext(last.level)
+		unlockNodes(nodes)
+
+		list.release(lo)
+		list.release(hi)
+
+		list.lock.Lock()
+		list.nIntervals--
+		list.lock.Unlock()
+
+		return true
+	}
+}
+
+/*Stab : Return every interval containing point, ordered by Lo then Hi.
+Thread safe. */
+func (list *IntervalSkiplist) Stab(point SkiplistItem) []Interval {
+	found := list.read(point, nil)
+	sortIntervals(found)
+	return found
+}
+
+/*Overlap : Return every interval sharing a point with [lo, hi),
+ordered by Lo then Hi. Thread safe. */
+func (list *IntervalSkiplist) Overlap(lo, hi SkiplistItem) []Interval {
+	if !lo.Less(hi) {
+		return nil
+	}
+
+	found := list.read(lo, hi)
+	sortIntervals(found)
+	return found
+}
+
+// read intervals containing point and, if hi is not nil, those starting
+// after point and before hi. Every node read is stamped before and checked
+// after, so the result is the one of the moment in between
+func (list *IntervalSkiplist) read(point, hi SkiplistItem) []Interval {
+
+	head := list.points.head
+	probe := &endpoint{key: point}
+
+	var prev, next [SkiplistMaxLevel]*skiplistNode
+	var nodes []*skiplistNode
+	var stamps []uint32
+	var found []Interval
+
+	// visit record the stamp of node, false if it is being changed
+	visit := func(node *skiplistNode) bool {
+		stamp := pointOf(node).loadStamp()
+		nodes = append(nodes, node)
+		stamps = append(stamps, stamp)
+		return stamp%2 == 0
+	}
+
+	for {
+		prev = [SkiplistMaxLevel]*skiplistNode{}
+		list.points.Find(probe, prev[:], next[:])
+		levels := 0
+		for levels < SkiplistMaxLevel && prev[levels] != nil {
+			levels++
+		}
+
+		nodes, stamps, found = nodes[:0], stamps[:0], found[:0]
+
+		// no level above the searched ones
+		valid := visit(head) && (levels == SkiplistMaxLevel || head.loadNext(levels) == nil)
+
+		// the edge holding point on each level
+		var last *skiplistNode
+		for level := levels - 1; valid && level >= 0; level-- {
+			last = prev[level]
+			if next[level] != nil && pointOf(next[level]).key.Equals(point) {
+				last = next[level]
+			}
+			valid = visit(last) && !last.loadMarked() && !endsBy(last.loadNext(level), point)
+			for _, entry := range pointOf(last).loadMarkers(level) {
+				found = append(found, entry.interval)
+			}
+		}
+
+		// then the intervals starting up to hi
+		if valid && hi != nil {
+			for curr := last.loadNext(0); valid && curr != nil && pointOf(curr).key.Less(hi); curr = curr.loadNext(0) {
+				valid = visit(curr)
+				for _, entry := range pointOf(curr).loadStarting() {
+					found = append(found, entry.interval)
+				}
+			}
+		}
+
+		for index := 0; valid && index < len(nodes); index++ {
+			valid = pointOf(nodes[index]).loadStamp() == stamps[index]
+		}
+		if valid {
+			return append([]Interval(nil), found...)
+		}
+	}
+}
+
+// acquire node for endpoint key, linking a new one if there is none
+func (list *IntervalSkiplist) acquire(key SkiplistItem) *skiplistNode {
+	probe := &endpoint{key: key}
+	var prev, next [SkiplistMaxLevel]*skiplistNode
+
+	// drawn once a node has to be created
+	topLevel := 0
+
+	for {
+		prev = [SkiplistMaxLevel]*skiplistNode{}
+		foundLevel := list.points.Find(probe, prev[:], next[:])
+
+		if foundLevel == -1 {
+			// search again, the list may have grown
+			if topLevel == 0 {
+				topLevel = list.points.randomLevel()
+				continue
+			}
+			if node := list.link(key, topLevel, prev[:], next[:]); node != nil {
+				return node
+			}
+			continue
+		}
+
+		node := next[foundLevel]
+		// being unlinked, try again
+		if node.loadMarked() {
+			continue
+		}
+		// wait until stable
+		for !node.loadFullyLinked() {
+		}
+
+		node.mux.Lock()
+		if node.loadMarked() {
+			node.mux.Unlock()
+			continue
+		}
+		pointOf(node).refs++
+		node.mux.Unlock()
+
+		return node
+	}
+}
+
+// release drop a reference to node, unlinking it once no interval
+// ends there
+func (list *IntervalSkiplist) release(node *skiplistNode) {
+	node.mux.Lock()
+	point := pointOf(node)
+	point.refs--
+	if point.refs > 0 {
+		node.mux.Unlock()
+		return
+	}
+	// no one acquires it anymore
+	node.storeMarked(true)
+	node.mux.Unlock()
+
+	list.unlink(node)
+}
+
+// link a node for key of topLevel levels between prev and next found by
+// a search, nil if they changed. Locks every node from prev on the top
+// level up to next on it, as the edges in between are marked again
+func (list *IntervalSkiplist) link(key SkiplistItem, topLevel int, prev, next []*skiplistNode) *skiplistNode {
+	top := topLevel - 1
+
+	// levels the search did not reach, checked below
+	for level := 0; level < topLevel; level++ {
+		if prev[level] == nil {
+			prev[level], next[level] = list.points.head, nil
+		}
+	}
+
+	region := span(prev[top], next[top])
+	if region == nil {
+		return nil
+	}
+	lockNodes(region)
+
+	valid := sameNodes(region, span(prev[top], next[top]))
+	for level := 0; valid && level < topLevel; level++ {
+		pred, succ := prev[level], next[level]
+		valid = holds(region, pred) && !pred.loadMarked() && (succ == nil || !succ.loadMarked()) && pred.loadNext(level) == succ
+	}
+	if !valid {
+		unlockNodes(region)
+		return nil
+	}
+
+	newNode := new(skiplistNode)
+	newNode.value = &endpoint{key: key, refs: 1, markers: make([]unsafe.Pointer, topLevel)}
+	newNode.topLevel = top
+
+	// held until linked, an acquire of the same key has to wait for it
+	newNode.mux.Lock()
+	stamp([]*skiplistNode{newNode})
+
+	list.rebuild(region, next[top], topLevel, func() {
+		for level := 0; level < topLevel; level++ {
+			newNode.storeNext(level, next[level])
+			prev[level].storeNext(level, newNode)
+		}
+
+		// backward link, prev[0] is locked
+		newNode.storePrev(prev[0])
+		if next[0] != nil {
+			next[0].storePrev(newNode)
+		} else {
+			list.points.storeTail(newNode)
+		}
+		newNode.storeFullyLinked(true)
+	})
+
+	stamp([]*skiplistNode{newNode})
+	newNode.mux.Unlock()
+	unlockNodes(region)
+
+	list.points.lock.Lock()
+	list.points.nElements++
+	list.points.nLevels = max(list.points.nLevels, topLevel)
+	list.points.lock.Unlock()
+
+	return newNode
+}
+
+// unlink remove node, marked once no interval ends there. Locks every
+// node from its predecessor on its top level up to its successor on it,
+// as the edges in between are marked again
+func (list *IntervalSkiplist) unlink(node *skiplistNode) {
+	top := node.topLevel
+	var prev, next [SkiplistMaxLevel]*skiplistNode
+
+	for {
+		prev = [SkiplistMaxLevel]*skiplistNode{}
+		list.points.Find(node.value, prev[:], next[:])
+
+		// levels the search did not reach, checked below
+		for level := 0; level <= top; level++ {
+			if prev[level] == nil {
+				prev[level] = list.points.head
+			}
+		}
+
+		end := node.loadNext(top)
+		region := span(prev[top], end)
+		if region == nil {
+			continue
+		}
+		lockNodes(region)
+
+		valid := sameNodes(region, span(prev[top], end)) && node.loadNext(top) == end
+		for level := 0; valid && level <= top; level++ {
+			pred := prev[level]
+			valid = holds(region, pred) && !pred.loadMarked() && pred.loadNext(level) == node
+		}
+		if !valid {
+			unlockNodes(region)
+			continue
+		}
+
+		list.rebuild(region, end, top+1, func() {
+			for level := top; level >= 0; level-- {
+				prev[level].storeNext(level, node.loadNext(level))
+			}
+
+			// backward link
+			if succ := node.loadNext(0); succ != nil {
+				succ.storePrev(prev[0])
+			} else {
+				list.points.storeTail(prev[0])
+			}
+		})
+		unlockNodes(region)
+
+		list.points.lock.Lock()
+		list.points.nElements--
+		list.points.shrink()
+		list.points.lock.Unlock()
+
+		return
+	}
+}
+
+// place mark the edges covering the interval of entry and add it to
+// the intervals starting at lo, false if an interval with the same
+// endpoints is there already
+func (list *IntervalSkiplist) place(entry *intervalEntry, lo *skiplistNode) bool {
+	hi := entry.interval.Hi
+
+	for {
+		// moved by a concurrent writer, try again
+		edges := cover(lo, hi)
+		if edges == nil {
+			continue
+		}
+
+		nodes := edgeNodes(edges)
+		lockNodes(nodes)
+		if !sameEdges(edges, cover(lo, hi)) {
+			unlockNodes(nodes)
+			continue
+		}
+
+		if pointOf(lo).lookup(hi) != nil {
+			unlockNodes(nodes)
+			return false
+		}
+
+		stamp(nodes)
+		for _, edge := range edges {
+			mark(edge, entry)
+		}
+		starting := pointOf(lo).loadStarting()
+		pointOf(lo).storeStarting(append(starting[:len(starting):len(starting)], entry))
+		stamp(nodes)
+		unlockNodes(nodes)
+
+		return true
+	}
+}
+
+// rebuild run change, relinking nodes between the locked region and
+// end, then mark again the intervals marking the edges of the region
+// below levels. Edges on higher levels either miss the region or span
+// all of it, so they are left alone
+func (list *IntervalSkiplist) rebuild(region []*skiplistNode, end *skiplistNode, levels int, change func()) {
+	stamp(region)
+
+	affected := make(map[*intervalEntry]bool)
+	for _, node := range region {
+		point := pointOf(node)
+		for level := 0; level < levels && level <= node.topLevel; level++ {
+			for _, entry := range point.loadMarkers(level) {
+				affected[entry] = true
+			}
+			point.storeMarkers(level, nil)
+		}
+	}
+
+	change()
+
+	// the part of each interval inside the region
+	head := list.points.head
+	for entry := range affected {
+		start := region[0]
+		for start == head || pointOf(start).key.Less(entry.interval.Lo) {
+			start = start.loadNext(0)
+		}
+		stop := entry.interval.Hi
+		if end != nil && pointOf(end).key.Less(stop) {
+			stop = pointOf(end).key
+		}
+
+		for _, edge := range cover(start, stop) {
+			mark(edge, entry)
+		}
+	}
+
+	stamp(region)
+}
+
+// cover edges partitioning [start, stop), the highest edge from each
+// node that ends by stop. Nil if the links changed during the walk
+func cover(start *skiplistNode, stop SkiplistItem) []intervalEdge {
+	var edges []intervalEdge
+	for curr := start; pointOf(curr).key.Less(stop); {
+		level := curr.topLevel
+		for level >= 0 && !endsBy(curr.loadNext(level), stop) {
+			level--
+		}
+		if level < 0 {
+			return nil
+		}
+		edges = append(edges, intervalEdge{curr, level})
+		curr = curr.loadNext(level)
+	}
+	return edges
+}
+
+// markedEdges edges holding a marker of entry from lo on, nil if they
+// do not reach the end of its interval
+func markedEdges(lo *skiplistNode, entry *intervalEntry) []intervalEdge {
+	var edges []intervalEdge
+	for curr := lo; curr != nil && pointOf(curr).key.Less(entry.interval.Hi); {
+		level := curr.topLevel
+		for level >= 0 && !holdsEntry(pointOf(curr).loadMarkers(level), entry) {
+			level--
+		}
+		if level < 0 {
+			return nil
+		}
+		edges = append(edges, intervalEdge{curr, level})
+		curr = curr.loadNext(level)
+	}
+	return edges
+}
+
+// mark add a marker of entry to edge, called with its node locked
+func mark(edge intervalEdge, entry *intervalEntry) {
+	point := pointOf(edge.node)
+	markers := point.loadMarkers(edge.level)
+	point.storeMarkers(edge.level, append(markers[:len(markers):len(markers)], entry))
+}
+
+// unmark remove the marker of entry from edge, called with its node locked
+func unmark(edge intervalEdge, entry *intervalEntry) {
+	point := pointOf(edge.node)
+	point.storeMarkers(edge.level, without(point.loadMarkers(edge.level), entry))
+}
+
+// pointOf endpoint held by node
+func pointOf(node *skiplistNode) *endpoint {
+	return node.value.(*endpoint)
+}
+
+// endsBy true if node is not nil and its key is not after key
+func endsBy(node *skiplistNode, key SkiplistItem) bool {
+	return node != nil && !key.Less(pointOf(node).key)
+}
+
+// lookup interval starting at the endpoint and ending at hi, nil if none
+func (point *endpoint) lookup(hi SkiplistItem) *intervalEntry {
+	for _, entry := range point.loadStarting() {
+		if entry.interval.Hi.Equals(hi) {
+			return entry
+		}
+	}
+	return nil
+}
+
+// loadMarkers intervals marking the edge leaving the node on level
+func (point *endpoint) loadMarkers(level int) []*intervalEntry {
+	return loadEntries(&point.markers[level])
+}
+
+// storeMarkers set the intervals marking the edge leaving the node on level
+func (point *endpoint) storeMarkers(level int, entries []*intervalEntry) {
+	storeEntries(&point.markers[level], entries)
+}
+
+// loadStarting intervals whose Lo is the key
+func (point *endpoint) loadStarting() []*intervalEntry {
+	return loadEntries(&point.starting)
+}
+
+// storeStarting set the intervals whose Lo is the key
+func (point *endpoint) storeStarting(entries []*intervalEntry) {
+	storeEntries(&point.starting, entries)
+}
+
+// loadStamp odd while the node changes, bumped by every change
+func (point *endpoint) loadStamp() uint32 {
+	return atomic.LoadUint32(&point.stamp)
+}
+
+// loadEntries slice stored at p, nil if none
+func loadEntries(p *unsafe.Pointer) []*intervalEntry {
+	if entries := (*[]*intervalEntry)(atomic.LoadPointer(p)); entries != nil {
+		return *entries
+	}
+	return nil
+}
+
+// storeEntries store entries at p, they must not be changed afterwards
+func storeEntries(p *unsafe.Pointer, entries []*intervalEntry) {
+	atomic.StorePointer(p, unsafe.Pointer(&entries))
+}
+
+// stamp bump the stamps of nodes, before and after changing them
+func stamp(nodes []*skiplistNode) {
+	for _, node := range nodes {
+		atomic.AddUint32(&pointOf(node).stamp, 1)
+	}
+}
+
+// without copy of entries leaving out entry
+func without(entries []*intervalEntry, entry *intervalEntry) []*intervalEntry {
+	kept := make([]*intervalEntry, 0, len(entries))
+	for _, other := range entries {
+		if other != entry {
+			kept = append(kept, other)
+		}
+	}
+	return kept
+}
+
+// holdsEntry true if entry is one of entries
+func holdsEntry(entries []*intervalEntry, entry *intervalEntry) bool {
+	for _, other := range entries {
+		if other == entry {
+			return true
+		}
+	}
+	return false
+}
+
+// span nodes of the lowest level from start up to end excluded,
+// nil if the walk passes end
+func span(start, end *skiplistNode) []*skiplistNode {
+	nodes := []*skiplistNode{start}
+	for curr := start.loadNext(0); curr != end; curr = curr.loadNext(0) {
+		if curr == nil || (end != nil && !pointOf(curr).key.Less(pointOf(end).key)) {
+			return nil
+		}
+		nodes = append(nodes, curr)
+	}
+	return nodes
+}
+
+// edgeNodes nodes the edges leave
+func edgeNodes(edges []intervalEdge) []*skiplistNode {
+	nodes := make([]*skiplistNode, len(edges))
+	for index, edge := range edges {
+		nodes[index] = edge.node
+	}
+	return nodes
+}
+
+// sameEdges true if a and b hold the same edges
+func sameEdges(a, b []intervalEdge) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for index := range a {
+		if a[index] != b[index] {
+			return false
+		}
+	}
+	return true
+}
+
+// sameNodes true if a and b hold the same nodes
+func sameNodes(a, b []*skiplistNode) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for index := range a {
+		if a[index] != b[index] {
+			return false
+		}
+	}
+	return true
+}
+
+// holds true if node is one of nodes
+func holds(nodes []*skiplistNode, node *skiplistNode) bool {
+	for _, other := range nodes {
+		if other == node {
+			return true
+		}
+	}
+	return false
+}
+
+// lockNodes lock nodes given by ascending item in descending order,
+// the order every writer of a Skiplist locks in
+func lockNodes(nodes []*skiplistNode) {
+	for index := len(nodes) - 1; index >= 0; index-- {
+		nodes[index].mux.Lock()
+	}
+}
+
+// unlockNodes unlock nodes locked by lockNodes
+func unlockNodes(nodes []*skiplistNode) {
+	for _, node := range nodes {
+		node.mux.Unlock()
+	}
+}
+
+// sortIntervals order intervals by Lo then Hi
+func sortIntervals(intervals []Interval) {
+	sort.Slice(intervals, func(i, j int) bool {
+		a, b := intervals[i], intervals[j]
+		if a.Lo.Less(b.Lo) {
+			return true
+		}
+		return a.Lo.Equals(b.Lo) && a.Hi.Less(b.Hi)
+	})
+}
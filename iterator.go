@@ -0,0 +1,133 @@
+package goskiplist
+
+/*Iterator : Sequence of Skiplist items.
+Next advances to the next item and returns false when exhausted,
+Item returns the current item. */
+type Iterator interface {
+	Next() bool
+	Item() SkiplistItem
+}
+
+/*SeekIterator : Iterator that can also jump ahead.
+Seek moves to the first item not less than val and returns false
+if there is none, Item then returns it. */
+type SeekIterator interface {
+	Iterator
+	Seek(val SkiplistItem) bool
+}
+
+/*ListIterator : Iterator over the items of a Skiplist, in ascending or
+descending order. Items inserted or removed concurrently may or may not
+be seen, removed items are never returned once their removal started. */
+type ListIterator struct {
+	list    *Skiplist
+	node    *skiplistNode
+	reverse bool
+}
+
+/*Iterator : Return an iterator over the items of the Skiplist in ascending order.
+Thread safe. */
+func (list *Skiplist) Iterator() *ListIterator {
+	return &ListIterator{list: list}
+}
+
+/*ReverseIterator : Return an iterator over the items of the Skiplist in
+descending order, following the backward links of the lowest level.
+Thread safe. */
+func (list *Skiplist) ReverseIterator() *ListIterator {
+	return &ListIterator{list: list, reverse: true}
+}
+
+// Next advance to the next item, false when exhausted
+func (it *ListIterator) Next() bool {
+	head := it.list.head
+
+	// exhausted, stay exhausted
+	node := it.node
+	if node == head {
+		return false
+	}
+
+	for {
+		switch {
+		case node == nil && it.reverse:
+			node = it.list.loadTail()
+		case node == nil:
+			node = head.loadNext(0)
+		case it.reverse:
+			node = node.loadPrev()
+		default:
+			node = node.loadNext(0)
+		}
+
+		// exhausted, stay exhausted
+		if node == nil || node == head {
+			it.node = head
+			return false
+		}
+
+		if it.list.isLive(node) {
+			it.node = node
+			return true
+		}
+	}
+}
+
+// Seek move to the first item not less than val, or not greater
+// than val for a reverse iterator, false if there is none
+func (it *ListIterator) Seek(val SkiplistItem) bool {
+	list := it.list
+	head := list.head
+
+	var prev, next [SkiplistMaxLevel]*skiplistNode
+	list.Find(val, prev[:], next[:])
+
+	var node *skiplistNode
+	if !it.reverse {
+		node = next[0]
+		for node != nil && !list.isLive(node) {
+			node = node.loadNext(0)
+		}
+	} else {
+		node = prev[0]
+		if next[0] != nil && next[0].value.Equals(val) {
+			node = next[0]
+		}
+		for node != nil && node != head && !list.isLive(node) {
+			node = node.loadPrev()
+		}
+	}
+
+	// nothing left, exhausted
+	if node == nil || node == head {
+		it.node = head
+		return false
+	}
+
+	it.node = node
+	return true
+}
+
+// Item current item, nil before the first call to Next
+// or once exhausted
+func (it *ListIterator) Item() SkiplistItem {
+	if it.node == nil {
+		return nil
+	}
+	return it.node.item()
+}
+
+/*Min : Return the smallest item of the Skiplist, nil if it is empty. Thread safe. */
+func (list *Skiplist) Min() SkiplistItem {
+	it := list.Iterator()
+	it.Next()
+	return it.Item()
+}
+
+/*Max : Return the largest item of the Skiplist, nil if it is empty.
+O(1) unless the last items are being removed. Thread safe. */
+func (list *Skiplist) Max() SkiplistItem {
+	it := list.ReverseIterator()
+	it.Next()
+	return it.Item()
+}
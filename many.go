@@ -0,0 +1,156 @@
+package goskiplist
+
+import (
+	"context"
+	"sort"
+)
+
+/*InsertMany : Insert every item of items to the Skiplist.
+The batch is handled in ascending order, every search starts from the
+position of the previous one instead of the head.
+Every item is inserted as by Insert, results[i] reports whether items[i]
+was inserted. Thread safe. */
+func (list *Skiplist) InsertMany(items []SkiplistItem) (results []bool) {
+
+	results = make([]bool, len(items))
+
+	// buffers to store prev and next pointers,
+	// kept between items as a finger
+	prev := make([]*skiplistNode, SkiplistMaxLevel)
+	next := make([]*skiplistNode, SkiplistMaxLevel)
+
+	expires := list.defaultExpiry()
+	for _, index := range sortedOrder(items) {
+		results[index] = list.insert(context.Background(), items[index], prev, next, true, expires) == nil
+	}
+
+	return results
+}
+
+/*RemoveMany : Remove every item of items from the Skiplist.
+The batch is handled in ascending order, every search starts from the
+position of the previous one instead of the head.
+Every item is removed as by Remove, results[i] reports whether items[i]
+was removed. Thread safe. */
+func (list *Skiplist) RemoveMany(items []SkiplistItem) (results []bool) {
+
+	results = make([]bool, len(items))
+
+	// buffers to store prev and next pointers,
+	// kept between items as a finger
+	prev := make([]*skiplistNode, SkiplistMaxLevel)
+	next := make([]*skiplistNode, SkiplistMaxLevel)
+
+	for _, index := range sortedOrder(items) {
+		results[index] = list.remove(context.Background(), items[index], prev, next, true) == nil
+	}
+
+	return results
+}
+
+// sortedOrder indices of items in ascending item order,
+// equal items keep their order
+func sortedOrder(items []SkiplistItem) []int {
+	order := make([]int, len(items))
+	for index := range order {
+		order[index] = index
+	}
+
+	// common case, already sorted
+	sorted := true
+	for index := 1; sorted && index < len(items); index++ {
+		sorted = !items[index].Less(items[index-1])
+	}
+	if sorted {
+		return order
+	}
+
+	sort.SliceStable(order, func(i, j int) bool {
+		return items[order[i]].Less(items[order[j]])
+	})
+
+	return order
+}
+
+/*search : Find, but if finger is set the search starts from the nodes
+left in prev by an earlier search. It climbs from the lowest level only
+as high as needed to pass val, or to get back before it, and then descends,
+falling back to Find when the finger was removed or changed underneath. */
+func (list *Skiplist) search(val SkiplistItem, prev, next []*skiplistNode, finger bool) (foundLevel int) {
+
+	if !finger {
+		return list.Find(val, prev, next)
+	}
+
+	// could be modified by inserts
+	list.lock.RLock()
+	levels := list.nLevels
+	list.lock.RUnlock()
+
+	// every level must still hold a linked node of the earlier search,
+	// its predecessors are ordered so higher levels are never ahead
+	for level := 0; level < levels; level++ {
+		if prev[level] == nil || prev[level].loadMarked() {
+			return list.Find(val, prev, next)
+		}
+	}
+
+	top := 0
+	if prev[0] != list.head && !prev[0].value.Less(val) {
+		// behind the finger, climb until a predecessor is before val,
+		// predecessors of higher levels are further back
+		for top+1 < levels && prev[top] != list.head && !prev[top].value.Less(val) {
+			top++
+		}
+		if prev[top] != list.head && !prev[top].value.Less(val) {
+			return list.Find(val, prev, next)
+		}
+	} else {
+		// climb while the next level still has to move forward
+		for top+1 < levels {
+			succ := prev[top+1].loadNext(top+1)
+			if succ == nil || !succ.value.Less(val) {
+				break
+			}
+			top++
+		}
+	}
+
+	// levels above top are still in place, unless
+	// a concurrent insert linked a lesser node after them
+	for level := top + 1; level < levels; level++ {
+		next[level] = prev[level].loadNext(level)
+		if next[level] != nil && next[level].value.Less(val) {
+			return list.Find(val, prev, next)
+		}
+	}
+
+	// descend from the finger
+	pred := prev[top]
+	var curr *skiplistNode
+	for level := top; level >= 0; level-- {
+		// horizontally
+		curr = pred.loadNext(level)
+		for curr != nil && curr.value.Less(val) {
+			pred = curr
+			curr = pred.loadNext(level)
+		}
+
+		// previous of where the item should be
+		prev[level] = pred
+		next[level] = curr
+	}
+
+	// highest level the found node is linked on
+	foundLevel = -1
+	if curr != nil && curr.value.Equals(val) {
+		for level := levels - 1; level >= 0; level-- {
+			if next[level] == curr {
+				foundLevel = level
+				break
+			}
+		}
+	}
+
+	return foundLevel
+}
@@ -0,0 +1,121 @@
+package goskiplist
+
+import (
+	"math/rand"
+)
+
+/*PopMin : Remove and return the smallest item of the Skiplist,
+nil if it is empty. Concurrent poppers losing the race for an item
+move on to the next one instead of starting over. Thread safe. */
+func (list *Skiplist) PopMin() SkiplistItem {
+	return list.popFrom(list.head.loadNext(0))
+}
+
+/*PopMinRelaxed : Remove and return an item close to the smallest of the
+Skiplist, nil if it is empty. Every call sprays to a random position among
+roughly the first width items as in the SprayList, so concurrent poppers
+claim different items instead of fighting over the first one.
+width should be about the amount of concurrent poppers,
+a width of 1 or less behaves like PopMin. Thread safe. */
+func (list *Skiplist) PopMinRelaxed(width int) SkiplistItem {
+	if width <= 1 {
+		return list.PopMin()
+	}
+
+	if item := list.popFrom(list.spray(width)); item != nil {
+		return item
+	}
+
+	// nothing left after the landing position
+	return list.PopMin()
+}
+
+/*PopMax : Remove and return the largest item of the Skiplist,
+nil if it is empty. Thread safe. */
+func (list *Skiplist) PopMax() SkiplistItem {
+
+	// taken or still being inserted, try the predecessor
+	for target := list.loadTail(); target != list.head; target = target.loadPrev() {
+		if !list.isLive(target) {
+			continue
+		}
+		if claimed, unlink := list.claim(target); claimed {
+			return list.popClaimed(target, unlink)
+		}
+	}
+
+	return nil
+}
+
+// popFrom claim and remove the first available node from start on,
+// nil if none is left
+func (list *Skiplist) popFrom(start *skiplistNode) SkiplistItem {
+	for curr := start; curr != nil; curr = curr.loadNext(0) {
+		if !list.isLive(curr) {
+			continue
+		}
+
+		if claimed, unlink := list.claim(curr); claimed {
+			return list.popClaimed(curr, unlink)
+		}
+	}
+
+	return nil
+}
+
+// claim remove node logically, claimed is false if it was
+// already removed, is not fully linked or is part of a pending
+// batch, unlink is false
+// if the node stays linked as a tombstone for snapshots
+func (list *Skiplist) claim(node *skiplistNode) (claimed, unlink bool) {
+	node.mux.Lock()
+	defer node.mux.Unlock()
+
+	if !node.loadFullyLinked() || node.loadMarked() || node.loadOwner() != nil || node.tombstoned() {
+		return false, false
+	}
+
+	return true, list.retire(node)
+}
+
+// popClaimed unlink a claimed node if needed and return its item
+func (list *Skiplist) popClaimed(node *skiplistNode, unlink bool) SkiplistItem {
+	if unlink {
+		list.unlinkMarked([]*skiplistNode{node})
+	}
+
+	// update element count
+	list.lock.Lock()
+	list.nElements--
+	list.lock.Unlock()
+
+	list.dispatch()
+	return node.item()
+}
+
+// spray random walk from the head: starting at the level of log2(width)
+// it moves forward a random amount of up to log2(width)+1 nodes
+// on every level and then drops a level
+func (list *Skiplist) spray(width int) *skiplistNode {
+	height := 0
+	for w := width; w > 1; w >>= 1 {
+		height++
+	}
+
+	list.lock.RLock()
+	height = min(height, list.nLevels-1)
+	list.lock.RUnlock()
+
+	jump := height + 1
+	node := list.head
+	for level := height; level >= 0; level-- {
+		for steps := rand.Intn(jump + 1); steps > 0 && node.loadNext(level) != nil; steps-- {
+			node = node.loadNext(level)
+		}
+	}
+
+	if node == list.head {
+		return list.head.loadNext(0)
+	}
+	return node
+}
@@ -0,0 +1,88 @@
+package goskiplist
+
+/*Equal : Return true if both Skiplists hold equal items.
+Walks both in step and stops at the first difference.
+Items changed concurrently may or may not be seen.
+O(N + M), Thread safe */
+func (list *Skiplist) Equal(other *Skiplist) bool {
+
+	aptr := list.nextLive(list.head.loadNext(0))
+	bptr := other.nextLive(other.head.loadNext(0))
+
+	for aptr != nil && bptr != nil {
+		if !aptr.value.Equals(bptr.value) {
+			return false
+		}
+		aptr = list.nextLive(aptr.loadNext(0))
+		bptr = other.nextLive(bptr.loadNext(0))
+	}
+
+	return aptr == nil && bptr == nil
+}
+
+/*SubsetOf : Return true if every item of the Skiplist is in other.
+Stops at the first missing item, other is searched with a finger to
+skip its items that are not in the Skiplist, O(log d) for d items skipped.
+Items changed concurrently may or may not be seen.
+O(N + M) at most, Thread safe */
+func (list *Skiplist) SubsetOf(other *Skiplist) bool {
+
+	bfinger := other.Finger()
+	aptr := list.nextLive(list.head.loadNext(0))
+	bptr := other.nextLive(other.head.loadNext(0))
+
+	for aptr != nil {
+		switch {
+		// missing from other
+		case bptr == nil || aptr.value.Less(bptr.value):
+			return false
+		case bptr.value.Less(aptr.value):
+			bptr = bfinger.seek(aptr.value)
+		default:
+			aptr = list.nextLive(aptr.loadNext(0))
+			bptr = other.nextLive(bptr.loadNext(0))
+		}
+	}
+
+	return true
+}
+
+/*Disjoint : Return true if the Skiplists have no item in common.
+Stops at the first common item, whichever list is behind catches up
+with a finger, O(log d) for d items skipped.
+Items changed concurrently may or may not be seen.
+O(N + M) at most, Thread safe */
+func (list *Skiplist) Disjoint(other *Skiplist) bool {
+
+	afinger, bfinger := list.Finger(), other.Finger()
+	aptr := list.nextLive(list.head.loadNext(0))
+	bptr := other.nextLive(other.head.loadNext(0))
+
+	for aptr != nil && bptr != nil {
+		switch {
+		case aptr.value.Less(bptr.value):
+			aptr = afinger.seek(bptr.value)
+		case bptr.value.Less(aptr.value):
+			bptr = bfinger.seek(aptr.value)
+		default:
+			// common item
+			return false
+		}
+	}
+
+	return true
+}
+
+/*Overlaps : Return true if the Skiplists have at least one item in common,
+the opposite of Disjoint. O(N + M) at most, Thread safe */
+func (list *Skiplist) Overlaps(other *Skiplist) bool {
+	return !list.Disjoint(other)
+}
+
+// nextLive first live node from node on, nil if none
+func (list *Skiplist) nextLive(node *skiplistNode) *skiplistNode {
+	for node != nil && !list.isLive(node) {
+		node = node.loadNext(0)
+	}
+	return node
+}
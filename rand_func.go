@@ -28,7 +28,8 @@ func coinTosses(prob float64, maxLevels int, fast bool) (counter int) {
 		resMask := rand.Uint64() & mask
 
 		// find first zero in float representation
-		for ; resMask&1 == 0; resMask >>= 1 {
+		// never taller than maxLevels
+		for ; resMask&1 == 0 && counter < maxLevels; resMask >>= 1 {
 			counter++
 		}
 
@@ -39,7 +40,7 @@ func coinTosses(prob float64, maxLevels int, fast bool) (counter int) {
 	// supports probability
 	// slower
 	res := rand.Float64()
-	for res < prob {
+	for res < prob && counter < maxLevels {
 		res = rand.Float64()
 		counter++
 	}
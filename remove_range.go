@@ -0,0 +1,176 @@
+package goskiplist
+
+import (
+	"runtime"
+)
+
+/*RemoveRange : Remove every item x with lo <= x < hi from the Skiplist.
+A nil lo or hi leaves that side of the range open.
+Every item is marked first, then each level is unlinked in a single pass.
+Items of a batch being applied concurrently are left alone.
+Returns the amount of items removed. Thread safe. */
+func (list *Skiplist) RemoveRange(lo, hi SkiplistItem) int {
+	return len(list.removeNodes(lo, hi, func(node *skiplistNode) bool {
+		return !list.expired(node)
+	}))
+}
+
+/*RemoveIf : Remove every item for which match returns true.
+match is called without any lock held and may be called
+for items removed concurrently.
+Returns the amount of items removed. Thread safe. */
+func (list *Skiplist) RemoveIf(match func(SkiplistItem) bool) int {
+	return len(list.removeNodes(nil, nil, func(node *skiplistNode) bool {
+		return !list.expired(node) && match(node.item())
+	}))
+}
+
+/* actual implementation, marks every node in [lo, hi) accepted by match
+(every node if match is nil) and unlinks them, nodes a snapshot may
+still see become tombstones instead. Returns the removed nodes. */
+func (list *Skiplist) removeNodes(lo, hi SkiplistItem, match func(*skiplistNode) bool) []*skiplistNode {
+
+	var curr *skiplistNode
+	if lo == nil {
+		curr = list.head.loadNext(0)
+	} else {
+		curr = list.findNextLowest(lo)
+	}
+
+	// mark phase, nodes are marked one at a time
+	// without holding more than a single lock
+	var removed, victims []*skiplistNode
+	for ; curr != nil && (hi == nil || curr.value.Less(hi)); curr = curr.loadNext(0) {
+		// items of a pending batch are skipped, waiting for the batch
+		// while holding marked nodes could block it
+		if curr.loadMarked() || !curr.loadFullyLinked() || curr.loadOwner() != nil || curr.tombstoned() || (match != nil && !match(curr)) {
+			continue
+		}
+
+		curr.mux.Lock()
+		// did some other routine remove it first?
+		if curr.loadFullyLinked() && !curr.loadMarked() && curr.loadOwner() == nil && !curr.tombstoned() {
+			removed = append(removed, curr)
+			// kept for snapshots unless marked
+			if list.retire(curr) {
+				victims = append(victims, curr)
+			}
+		}
+		curr.mux.Unlock()
+	}
+
+	if len(removed) == 0 {
+		return nil
+	}
+
+	if len(victims) > 0 {
+		list.unlinkMarked(victims)
+	}
+
+	// update element count
+	list.lock.Lock()
+	list.nElements -= len(removed)
+	list.lock.Unlock()
+
+	list.dispatch()
+
+	return removed
+}
+
+/*unlinkMarked : Physically unlink victims, which must be fully linked,
+marked by the caller and sorted in ascending order.
+Every level is unlinked in one pass, a level blocked by a node another
+routine is removing is left for later so that routine can go on. */
+func (list *Skiplist) unlinkMarked(victims []*skiplistNode) {
+
+	ours := make(map[*skiplistNode]bool, len(victims))
+	topLevel := 0
+	for _, victim := range victims {
+		ours[victim] = true
+		topLevel = max(topLevel, victim.topLevel)
+	}
+
+	// victims of each level and how many were unlinked
+	levelVictims := make([][]*skiplistNode, topLevel+1)
+	for _, victim := range victims {
+		for level := 0; level <= victim.topLevel; level++ {
+			levelVictims[level] = append(levelVictims[level], victim)
+		}
+	}
+	unlinked := make([]int, topLevel+1)
+
+	for pending := true; pending; {
+		pending = false
+		for level := topLevel; level >= 0; level-- {
+			if !list.unlinkLevel(level, levelVictims[level], ours, &unlinked[level]) {
+				pending = true
+			}
+		}
+		if pending {
+			runtime.Gosched()
+		}
+	}
+}
+
+// unlinkLevel unlink the victims of a level starting from victims[*unlinked],
+// returns false if it had to stop at a node marked by another routine
+func (list *Skiplist) unlinkLevel(level int, victims []*skiplistNode, ours map[*skiplistNode]bool, unlinked *int) bool {
+
+	if *unlinked == len(victims) {
+		return true
+	}
+
+	var prev, next [SkiplistMaxLevel]*skiplistNode
+	list.Find(victims[*unlinked].value, prev[:], next[:])
+	pred := prev[level]
+
+	for *unlinked < len(victims) {
+
+		// move forward without locking up to the next victim
+		succ := pred.loadNext(level)
+		for succ != nil && !ours[succ] {
+			pred = succ
+			succ = pred.loadNext(level)
+		}
+
+		// victims left but end reached, the structure
+		// changed since the search, start over later
+		if succ == nil {
+			return false
+		}
+
+		pred.mux.Lock()
+
+		// being removed by some other routine, come back later
+		if pred.loadMarked() {
+			pred.mux.Unlock()
+			return false
+		}
+
+		// skip every consecutive victim
+		succ = pred.loadNext(level)
+		skipped := false
+		for succ != nil && ours[succ] {
+			succ = succ.loadNext(level)
+			*unlinked++
+			skipped = true
+		}
+		if skipped {
+			pred.storeNext(level, succ)
+
+			// backward link, last skipped victim is marked
+			// so no one else can change it
+			if level == 0 {
+				if succ != nil {
+					succ.storePrev(pred)
+				} else {
+					list.storeTail(pred)
+				}
+			}
+		}
+
+		pred.mux.Unlock()
+	}
+
+	return true
+}
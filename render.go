@@ -0,0 +1,151 @@
+package goskiplist
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+)
+
+/*WriteDOT : Write the Skiplist structure to w in Graphviz DOT format.
+Every node is a column with one row per level, links are drawn between
+the rows they connect. Marked nodes are drawn red, nodes not yet
+fully linked are drawn dashed.
+Not threadsafe, call when no writers are active. */
+func (list *Skiplist) WriteDOT(w io.Writer) error {
+
+	buf := bufio.NewWriter(w)
+	nodes, ids := list.renderOrder()
+	levels := list.renderLevels()
+
+	fmt.Fprintln(buf, "digraph skiplist {")
+	fmt.Fprintln(buf, "\trankdir=LR;")
+	fmt.Fprintln(buf, "\tnode [shape=record, fontname=monospace];")
+
+	fmt.Fprintf(buf, "\thead [label=\"%s\"];\n", dotRecord("head", levels-1, levels))
+	for _, node := range nodes {
+		style := ""
+		if node.loadMarked() {
+			style += ", color=red, fontcolor=red"
+		}
+		if !node.loadFullyLinked() {
+			style += ", style=dashed"
+		}
+		fmt.Fprintf(buf, "\t%s [label=\"%s\"%s];\n", ids[node],
+			dotRecord(fmt.Sprint(node.value), node.topLevel, levels), style)
+	}
+	fmt.Fprintln(buf, "\tnil [shape=plaintext];")
+
+	for level := levels - 1; level >= 0; level-- {
+		from := "head"
+		for curr := list.head.loadNext(level); curr != nil; curr = curr.loadNext(level) {
+			fmt.Fprintf(buf, "\t%s:l%d -> %s:l%d;\n", from, level, ids[curr], level)
+			from = ids[curr]
+		}
+		fmt.Fprintf(buf, "\t%s:l%d -> nil;\n", from, level)
+	}
+
+	fmt.Fprintln(buf, "}")
+	return buf.Flush()
+}
+
+/*WriteASCII : Write a compact diagram of the Skiplist levels to w,
+highest level first. Marked nodes are suffixed with '*' and nodes not yet
+fully linked with '?'. Meant for small lists.
+Not threadsafe, call when no writers are active. */
+func (list *Skiplist) WriteASCII(w io.Writer) error {
+
+	buf := bufio.NewWriter(w)
+	nodes, _ := list.renderOrder()
+	levels := list.renderLevels()
+
+	labels := make([]string, len(nodes))
+	for index, node := range nodes {
+		labels[index] = fmt.Sprint(node.value)
+		if node.loadMarked() {
+			labels[index] += "*"
+		}
+		if !node.loadFullyLinked() {
+			labels[index] += "?"
+		}
+	}
+
+	for level := levels - 1; level >= 0; level-- {
+		// nodes linked on this level
+		linked := make(map[*skiplistNode]bool)
+		for curr := list.head.loadNext(level); curr != nil; curr = curr.loadNext(level) {
+			linked[curr] = true
+		}
+
+		row := make([]string, 0, len(nodes)+2)
+		row = append(row, "head")
+		for index, node := range nodes {
+			if linked[node] {
+				row = append(row, labels[index])
+			} else {
+				row = append(row, strings.Repeat("-", len(labels[index])))
+			}
+		}
+		row = append(row, "nil")
+
+		fmt.Fprintf(buf, "L%-2d %s\n", level, strings.Join(row, "--"))
+	}
+
+	return buf.Flush()
+}
+
+// renderOrder nodes in the order of the lowest level
+// followed by any node only reachable from upper levels,
+// along with a unique id for each
+func (list *Skiplist) renderOrder() ([]*skiplistNode, map[*skiplistNode]string) {
+	var nodes []*skiplistNode
+	ids := make(map[*skiplistNode]string)
+
+	for level := 0; level < SkiplistMaxLevel; level++ {
+		for curr := list.head.loadNext(level); curr != nil; curr = curr.loadNext(level) {
+			if _, seen := ids[curr]; seen {
+				continue
+			}
+			ids[curr] = fmt.Sprintf("n%d", len(nodes))
+			nodes = append(nodes, curr)
+		}
+	}
+
+	return nodes, ids
+}
+
+// renderLevels amount of levels to draw,
+// at least nLevels and every level with a link
+func (list *Skiplist) renderLevels() int {
+	levels := max(1, list.nLevels)
+	for level := levels; level < SkiplistMaxLevel; level++ {
+		if list.head.loadNext(level) != nil {
+			levels = level + 1
+		}
+	}
+	return levels
+}
+
+// dotRecord record label with one port per level up to topLevel,
+// highest level first and the label last
+func dotRecord(label string, topLevel, levels int) string {
+	fields := make([]string, 0, levels+1)
+	for level := levels - 1; level >= 0; level-- {
+		if level <= topLevel {
+			fields = append(fields, fmt.Sprintf("<l%d> ", level))
+		} else {
+			fields = append(fields, " ")
+		}
+	}
+	fields = append(fields, dotEscape(label))
+	return strings.Join(fields, "|")
+}
+
+// dotEscape escape characters with a meaning in record labels
+func dotEscape(label string) string {
+	replacer := strings.NewReplacer(
+		`\`, `\\`, `"`, `\"`, `|`, `\|`,
+		`{`, `\{`, `}`, `\}`, `<`, `\<`, `>`, `\>`,
+	)
+	return replacer.Replace(label)
+}
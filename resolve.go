@@ -0,0 +1,29 @@
+package goskiplist
+
+import (
+	"fmt"
+)
+
+/*Resolver : Decide the item kept for equal items a and b found in two
+Skiplists, such as summing counters, keeping the newest or merging structs.
+The result must equal both, as it takes their place in the order. */
+type Resolver func(a, b SkiplistItem) SkiplistItem
+
+/*MergeStats : What a merge of two Skiplists kept.
+FromA and FromB count the items of only one of them,
+Conflicts the items of both, resolved into one. */
+type MergeStats struct {
+	FromA     int
+	FromB     int
+	Conflicts int
+}
+
+// resolved item resolve keeps for a and b, anything not equal to
+// them would break the order of the merged Skiplist
+func resolved(op string, resolve Resolver, a, b SkiplistItem) SkiplistItem {
+	item := resolve(a, b)
+	if item == nil || !item.Equals(a) {
+		panic(fmt.Sprintf("%s: resolved item %v does not equal %v", op, item, a))
+	}
+	return item
+}
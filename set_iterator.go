@@ -0,0 +1,214 @@
+package goskiplist
+
+// setOp combination computed by a SetIterator
+type setOp int
+
+const (
+	setUnion setOp = iota
+	setIntersection
+	setDifference
+	setSymmetric
+)
+
+/*SetIterator : Lazy union, intersection, difference or symmetric
+difference of ascending iterators. Items are produced on demand by
+Next and Seek, nothing is materialized, so set iterators compose,
+as DifferenceIterator(IntersectionIterator(a, b), c) for (A ∩ B) − C.
+The inputs must not be used directly once combined. */
+type SetIterator struct {
+	op      setOp
+	inputs  []setInput
+	item    SkiplistItem
+	started bool
+}
+
+// setInput input of a SetIterator and its current item,
+// nil once exhausted
+type setInput struct {
+	it   SeekIterator
+	item SkiplistItem
+}
+
+func (input *setInput) next() {
+	input.item = nil
+	if input.it.Next() {
+		input.item = input.it.Item()
+	}
+}
+
+func (input *setInput) seek(val SkiplistItem) {
+	input.item = nil
+	if input.it.Seek(val) {
+		input.item = input.it.Item()
+	}
+}
+
+/*UnionIterator : Iterate over the items of any of its. */
+func UnionIterator(its ...SeekIterator) *SetIterator {
+	return newSetIterator(setUnion, its)
+}
+
+/*IntersectionIterator : Iterate over the items of every one of its,
+lagging inputs Seek to the largest current item. */
+func IntersectionIterator(its ...SeekIterator) *SetIterator {
+	return newSetIterator(setIntersection, its)
+}
+
+/*DifferenceIterator : Iterate over the items of from that are in none
+of others, others Seek to the current item of from. */
+func DifferenceIterator(from SeekIterator, others ...SeekIterator) *SetIterator {
+	return newSetIterator(setDifference, append([]SeekIterator{from}, others...))
+}
+
+/*SymmetricDifferenceIterator : Iterate over the items in an odd number
+of its, for two inputs the items in exactly one of them. */
+func SymmetricDifferenceIterator(its ...SeekIterator) *SetIterator {
+	return newSetIterator(setSymmetric, its)
+}
+
+func newSetIterator(op setOp, its []SeekIterator) *SetIterator {
+	inputs := make([]setInput, len(its))
+	for index, it := range its {
+		inputs[index].it = it
+	}
+	return &SetIterator{op: op, inputs: inputs}
+}
+
+// Next advance to the next item, false when exhausted
+func (it *SetIterator) Next() bool {
+	if !it.started {
+		it.started = true
+		for index := range it.inputs {
+			it.inputs[index].next()
+		}
+		return it.settle()
+	}
+
+	// exhausted, stay exhausted
+	if it.item == nil {
+		return false
+	}
+
+	switch it.op {
+	case setIntersection, setDifference:
+		// the others catch up while settling
+		it.inputs[0].next()
+	default:
+		it.advance(it.item)
+	}
+	return it.settle()
+}
+
+// Seek move to the first item not less than val, false if there is none
+func (it *SetIterator) Seek(val SkiplistItem) bool {
+	it.started = true
+	for index := range it.inputs {
+		it.inputs[index].seek(val)
+	}
+	return it.settle()
+}
+
+// Item current item, nil before the first call to Next
+// or once exhausted
+func (it *SetIterator) Item() SkiplistItem {
+	return it.item
+}
+
+// settle move the inputs until their current items make one of
+// the result, which becomes the current item
+func (it *SetIterator) settle() bool {
+	it.item = nil
+	inputs := it.inputs
+
+	for {
+		switch it.op {
+		case setUnion:
+			it.item = it.least()
+			return it.item != nil
+
+		case setSymmetric:
+			least := it.least()
+			if least == nil {
+				return false
+			}
+			if it.count(least)%2 == 1 {
+				it.item = least
+				return true
+			}
+			it.advance(least)
+
+		case setIntersection:
+			if len(inputs) == 0 {
+				return false
+			}
+			// every input must reach the largest current item
+			var largest SkiplistItem
+			for _, input := range inputs {
+				if input.item == nil {
+					return false
+				}
+				if largest == nil || largest.Less(input.item) {
+					largest = input.item
+				}
+			}
+			if it.count(largest) == len(inputs) {
+				it.item = largest
+				return true
+			}
+			for index := range inputs {
+				if inputs[index].item.Less(largest) {
+					inputs[index].seek(largest)
+				}
+			}
+
+		case setDifference:
+			candidate := inputs[0].item
+			if candidate == nil {
+				return false
+			}
+			excluded := false
+			for index := 1; index < len(inputs); index++ {
+				if inputs[index].item != nil && inputs[index].item.Less(candidate) {
+					inputs[index].seek(candidate)
+				}
+				excluded = excluded || (inputs[index].item != nil && inputs[index].item.Equals(candidate))
+			}
+			if !excluded {
+				it.item = candidate
+				return true
+			}
+			inputs[0].next()
+		}
+	}
+}
+
+// least smallest current item of the inputs, nil if all are exhausted
+func (it *SetIterator) least() SkiplistItem {
+	var least SkiplistItem
+	for _, input := range it.inputs {
+		if input.item != nil && (least == nil || input.item.Less(least)) {
+			least = input.item
+		}
+	}
+	return least
+}
+
+// count inputs whose current item equals val
+func (it *SetIterator) count(val SkiplistItem) int {
+	count := 0
+	for _, input := range it.inputs {
+		if input.item != nil && input.item.Equals(val) {
+			count++
+		}
+	}
+	return count
+}
+
+// advance move every input whose current item equals val to its next item
+func (it *SetIterator) advance(val SkiplistItem) {
+	for index := range it.inputs {
+		if it.inputs[index].item != nil && it.inputs[index].item.Equals(val) {
+			it.inputs[index].next()
+		}
+	}
+}
@@ -1,6 +1,7 @@
 package goskiplist
 
 import (
+	"context"
 	"fmt"
 )
 
@@ -60,16 +61,7 @@ func New(prob float64, maxLevels int, fastRandom bool) *Skiplist {
 
 	list := new(Skiplist)
 
-	if prob < 0 {
-		prob = 0.5
-		fmt.Println("Init: Probability given less than zero, set to 0.5 instead")
-	}
-
-	if maxLevels > SkiplistMaxLevel {
-		fmt.Println("Init: Max level given more than supported dataAmount of",
-			SkiplistMaxLevel, " setting to ", SkiplistMaxLevel, "instead")
-		maxLevels = SkiplistMaxLevel
-	}
+	prob, maxLevels = validateParams(prob, maxLevels)
 
 	list.nLevels = 1
 	list.prob = prob
@@ -77,28 +69,50 @@ func New(prob float64, maxLevels int, fastRandom bool) *Skiplist {
 	list.fastRandom = fastRandom
 
 	newHead := new(skiplistNode)
-	newHead.fullyLinked = true
-	newHead.marked = false
+	newHead.storeFullyLinked(true)
+	newHead.storeMarked(false)
 
 	list.nElements = 0
 
 	list.head = newHead
+	list.storeTail(newHead)
 
 	return list
 }
 
+// validateParams replace out of range parameters of New
+// by defaults, reporting what was replaced
+func validateParams(prob float64, maxLevels int) (float64, int) {
+	if prob < 0 {
+		prob = 0.5
+		fmt.Println("Init: Probability given less than zero, set to 0.5 instead")
+	}
+
+	if maxLevels > SkiplistMaxLevel {
+		fmt.Println("Init: Max level given more than supported dataAmount of",
+			SkiplistMaxLevel, " setting to ", SkiplistMaxLevel, "instead")
+		maxLevels = SkiplistMaxLevel
+	}
+
+	return prob, maxLevels
+}
+
 /*ToSortedArray : Return sorted array of inserted Skiplist items, not threadsafe */
 func (list *Skiplist) ToSortedArray() []SkiplistItem {
 	/* make a sorted array out of the Skiplist
 	   returns the lowest level               */
 	arr := make([]SkiplistItem, list.nElements, list.nElements)
 	counter := 0
-	for currentNode := list.head.next[0]; currentNode != nil; currentNode = currentNode.next[0] {
-		arr[counter] = currentNode.value
+	for currentNode := list.head.loadNext(0); currentNode != nil; currentNode = currentNode.loadNext(0) {
+		// expired and removed items are not returned
+		if !list.isLive(currentNode) {
+			continue
+		}
+		arr[counter] = currentNode.item()
 		counter++
 	}
 
-	return arr
+	return arr[:counter]
 
 }
 
@@ -120,10 +134,10 @@ func (list *Skiplist) findNextLowest(val SkiplistItem) (node *skiplistNode) {
 	// traverse vertically
 	for ; level >= 0; level-- {
 		// horizontally
-		curr = pred.next[level]
+		curr = pred.loadNext(level)
 		for curr != nil && curr.value.Less(val) {
 			pred = curr
-			curr = pred.next[level]
+			curr = pred.loadNext(level)
 		}
 
 		// next of where it should be
@@ -142,6 +156,11 @@ next and previous elements for every level.
 Returns the first level where it was found or
 -1 when not found */
 func (list *Skiplist) Find(val SkiplistItem, prev, next []*skiplistNode) (foundLevel int) {
+	return list.find(val, prev, next, nil)
+}
+
+/* actual implementation, records every step to trace if not nil */
+func (list *Skiplist) find(val SkiplistItem, prev, next []*skiplistNode, trace *SearchTrace) (foundLevel int) {
 
 	// could be modified by inserts
 	list.lock.RLock()
@@ -156,21 +175,24 @@ func (list *Skiplist) Find(val SkiplistItem, prev, next []*skiplistNode) (foundL
 	// traverse vertically
 	for ; level >= 0; level-- {
 		// horizontally
-		curr = pred.next[level]
-		for curr != nil && curr.value.Less(val) {
+		curr = pred.loadNext(level)
+		for curr != nil && trace.less(curr.value, val) {
+			trace.step(level, curr, TraceAdvance)
 			pred = curr
-			curr = pred.next[level]
+			curr = pred.loadNext(level)
 		}
 
 		// next of where it should be
-		if curr != nil && curr.value.Equals(val) && foundLevel == -1 {
+		if curr != nil && trace.equals(curr.value, val) && foundLevel == -1 {
 			foundLevel = level
+			trace.step(level, curr, TraceFound)
 		}
 
 		// previous of where the item should be
 		prev[level] = pred
 		next[level] = curr
 
+		trace.step(level, curr, TraceDrop)
 	}
 
 	return foundLevel
@@ -189,17 +211,16 @@ func (list *Skiplist) Contains(val SkiplistItem) bool {
 	// vertically
 	for ; level >= 0; level-- {
 		// horizontally
-		curr = pred.next[level]
+		curr = pred.loadNext(level)
 		for curr != nil && curr.value.Less(val) {
 			pred = curr
-			curr = pred.next[level]
+			curr = pred.loadNext(level)
 		}
 		//found something or have to go down
 
 		// is the next element what I seek
 		if curr != nil && curr.value.Equals(val) {
-			node := curr
-			return node.fullyLinked && !node.marked
+			return list.isLive(curr)
 		}
 	}
 	// not found
@@ -218,47 +239,65 @@ func (list *Skiplist) Get(val SkiplistItem) SkiplistItem {
 	// vertically
 	for ; level >= 0; level-- {
 		// horizontally
-		curr = pred.next[level]
+		curr = pred.loadNext(level)
 		for curr != nil && curr.value.Less(val) {
 			pred = curr
-			curr = pred.next[level]
+			curr = pred.loadNext(level)
 		}
 		//found something or have to go down
 
 		// is the next element what I seek
-		if curr != nil && curr.value.Equals(val) && curr.fullyLinked && !curr.marked {
-			return curr.value
+		if curr != nil && curr.value.Equals(val) && list.isLive(curr) {
+			return curr.item()
 		}
 	}
 	// not found
 	return nil
 }
 
-/*Insert : Insert node with value v to Skiplist. Returns true on success,false on failure to insert.
-Thread safe. */
+/*Insert : Insert node with value v to Skiplist. Returns true on success,false on failure to insert,
+see TryInsert for the reason. Thread safe. */
 func (list *Skiplist) Insert(v SkiplistItem) bool {
-	// insert element
-
-	// highest level of insertion
-	// the list.fast property should not be modified after init
-	topLevel := coinTosses(list.prob, list.maxLevels, list.fastRandom)
+	return list.TryInsert(v) == nil
+}
 
-	// check if list must become taller
-	list.lock.Lock()
-	if topLevel > list.nLevels {
-		list.nLevels = topLevel
-	}
-	list.lock.Unlock()
+/*TryInsert : Insert node with value v to Skiplist.
+Returns nil on success, ErrExists if an equal item is already there.
+Thread safe. */
+func (list *Skiplist) TryInsert(v SkiplistItem) error {
+	return list.TryInsertContext(context.Background(), v)
+}
 
+/*TryInsertContext : TryInsert, giving up with ErrCancelled once ctx is done.
+ctx is checked before every attempt, an insert that took effect is never undone.
+Thread safe. */
+func (list *Skiplist) TryInsertContext(ctx context.Context, v SkiplistItem) error {
 	// buffers to store prev and next pointers
-	var prev, next []*skiplistNode
-	prev = make([]*skiplistNode, SkiplistMaxLevel)
-	next = make([]*skiplistNode, SkiplistMaxLevel)
+	var prev, next [SkiplistMaxLevel]*skiplistNode
+
+	return list.insert(ctx, v, prev[:], next[:], false, list.defaultExpiry())
+}
+
+/* actual implementation, if finger is set the search starts
+from the nodes left in prev by an earlier search,
+expires is the expiration time of the new node */
+func (list *Skiplist) insert(ctx context.Context, v SkiplistItem, prev, next []*skiplistNode, finger bool, expires int64) error {
+	// events are delivered once every lock is released
+	defer list.dispatch()
+
+	// insert element
+
+	// highest level of insertion
+	topLevel := list.randomLevel()
 
 	for {
+		// nothing done yet, safe to give up
+		if err := cancelled(ctx); err != nil {
+			return err
+		}
 
 		// find insertion point and previous and next nodes
-		foundLevel := list.Find(v, prev, next)
+		foundLevel := list.search(v, prev, next, finger)
 
 		// already in Skiplist
 		if foundLevel != -1 {
@@ -266,76 +305,113 @@ func (list *Skiplist) Insert(v SkiplistItem) bool {
 			// should be the node with value v
 			nodeFound := next[foundLevel]
 			// if node is not set for removal
-			if !nodeFound.marked {
+			if !nodeFound.loadMarked() {
 				// wait until stable
-				for !nodeFound.fullyLinked {
+				for !nodeFound.loadFullyLinked() {
+				}
+				// part of a pending batch, wait for it
+				if nodeFound.loadOwner() != nil {
+					list.await(nodeFound)
+					continue
+				}
+				// kept for snapshots, insert a new version
+				if nodeFound.tombstoned() {
+					if list.revive(nodeFound, v, expires) {
+						return nil
+					}
+					continue
+				}
+				// expired, remove it and try again
+				if list.expired(nodeFound) {
+					list.expire(nodeFound)
+					continue
 				}
 				//don't insert
-				return false
+				return ErrExists
 			}
 			// try again
 			continue
 
 		}
-		// highest level locked
-		highestLocked := -1
-		var pred, succ *skiplistNode
-		var prevPred *skiplistNode
+		// try to add new node
+		newNode := new(skiplistNode)
+		newNode.value = v
+		newNode.topLevel = topLevel - 1
 
-		valid := true
+		if !list.link(newNode, prev, next) {
+			// restart attempt
+			continue
+		}
 
-		// validate that new node can be added
-		// by checking previous and next nodes
-		for level := 0; valid && level < topLevel; level++ {
+		// new node is ok
+		list.commitVersion(newNode, v, expires, EventInsert)
+		newNode.mux.Unlock()
 
-			pred = prev[level]
-			succ = next[level]
+		list.lock.Lock()
+		list.nElements = list.nElements + 1
+		list.lock.Unlock()
 
-			// avoid locking same node twice
-			// if two or more levels
-			// connected to same node
-			if pred != prevPred {
-				pred.mux.Lock()
+		list.enforceCapacity()
 
-				highestLocked = level
-				prevPred = pred
-			}
+		return nil
+	}
 
-			// can the insertion proceed
-			// node is locked so we can check next
-			valid = !pred.marked && (succ == nil || !succ.marked) && pred.next[level] == succ
-		}
+}
 
-		// cannot add
-		if !valid {
-			// unlock to try again
-			prevPred = nil
-			for i := highestLocked; i >= 0; i-- {
-				if prevPred != prev[i] {
-					prev[i].mux.Unlock()
-				}
-				prevPred = prev[i]
+/* highest level of insertion for a new node, grows the list if needed */
+func (list *Skiplist) randomLevel() int {
+	// the list.fast property should not be modified after init
+	topLevel := coinTosses(list.prob, list.maxLevels, list.fastRandom)
 
-			}
-			// restart attempt
-			continue
-		}
+	// check if list must become taller
+	list.lock.Lock()
+	if topLevel > list.nLevels {
+		list.nLevels = topLevel
+	}
+	list.lock.Unlock()
 
-		// try to add new node
-		newNode := new(skiplistNode)
-		newNode.value = v
-		newNode.topLevel = topLevel - 1
-		newNode.marked = false
+	return topLevel
+}
+
+/* link newNode between prev and next found by a search,
+false if they changed and the search has to start over.
+On success newNode is left locked, so no remove of the
+same item can start before it is committed */
+func (list *Skiplist) link(newNode *skiplistNode, prev, next []*skiplistNode) bool {
+	topLevel := newNode.topLevel + 1
+
+	// highest level locked
+	highestLocked := -1
+	var pred, succ *skiplistNode
+	var prevPred *skiplistNode
+
+	valid := true
 
-		for level := 0; level < topLevel; level++ {
+	// validate that new node can be added
+	// by checking previous and next nodes
+	for level := 0; valid && level < topLevel; level++ {
 
-			newNode.next[level] = next[level]
-			prev[level].next[level] = newNode
+		pred = prev[level]
+		succ = next[level]
+
+		// avoid locking same node twice
+		// if two or more levels
+		// connected to same node
+		if pred != prevPred {
+			pred.mux.Lock()
+
+			highestLocked = level
+			prevPred = pred
 		}
-		// new node is ok
-		newNode.fullyLinked = true
 
-		//unlock
+		// can the insertion proceed
+		// node is locked so we can check next
+		valid = !pred.loadMarked() && (succ == nil || !succ.loadMarked()) && pred.loadNext(level) == succ
+	}
+
+	// cannot add
+	if !valid {
+		// unlock to try again
 		prevPred = nil
 		for i := highestLocked; i >= 0; i-- {
 			if prevPred != prev[i] {
@@ -344,36 +420,97 @@ func (list *Skiplist) Insert(v SkiplistItem) bool {
 			prevPred = prev[i]
 
 		}
+		return false
+	}
 
-		list.lock.Lock()
-		list.nElements = list.nElements + 1
-		list.lock.Unlock()
+	newNode.storePrev(prev[0])
+
+	// held until the insert is published,
+	// a remove of the same item has to wait for it
+	newNode.mux.Lock()
 
-		return true
+	for level := 0; level < topLevel; level++ {
+
+		newNode.storeNext(level, next[level])
+		prev[level].storeNext(level, newNode)
+	}
+
+	// backward link, prev[0] is locked
+	if next[0] != nil {
+		next[0].storePrev(newNode)
+	} else {
+		list.storeTail(newNode)
 	}
 
+	//unlock
+	prevPred = nil
+	for i := highestLocked; i >= 0; i-- {
+		if prevPred != prev[i] {
+			prev[i].mux.Unlock()
+		}
+		prevPred = prev[i]
+
+	}
+
+	return true
 }
 
 /*Remove : Remove node with value val from Skiplist, if ite exists. Returns true on success,
-false on not found or failure to remove. Thread safe. */
+false on not found or failure to remove, see TryRemove for the reason. Thread safe. */
 func (list *Skiplist) Remove(val SkiplistItem) bool {
+	return list.TryRemove(val) == nil
+}
+
+/*TryRemove : Remove node with value val from Skiplist.
+Returns nil on success, ErrNotFound if there is no such item and
+ErrConcurrentRemoval if an other routine removed it first. Thread safe. */
+func (list *Skiplist) TryRemove(val SkiplistItem) error {
+	return list.TryRemoveContext(context.Background(), val)
+}
+
+/*TryRemoveContext : TryRemove, giving up with ErrCancelled once ctx is done.
+ctx is checked before every attempt until the item is marked, a marked
+item is always unlinked. Thread safe. */
+func (list *Skiplist) TryRemoveContext(ctx context.Context, val SkiplistItem) error {
+	var prev, next [SkiplistMaxLevel]*skiplistNode
+
+	return list.remove(ctx, val, prev[:], next[:], false)
+}
+
+/* actual implementation, if finger is set the search starts
+from the nodes left in prev by an earlier search */
+func (list *Skiplist) remove(ctx context.Context, val SkiplistItem, prev, next []*skiplistNode, finger bool) error {
+	// events are delivered once every lock is released
+	defer list.dispatch()
+
 	/* remove node */
 
 	var nodeToDelete *skiplistNode
 	isMarked := false
 	topLevel := -1
 
-	var prev, next [SkiplistMaxLevel]*skiplistNode
-
 	for {
+		// once marked the removal has to be finished
+		if !isMarked {
+			if err := cancelled(ctx); err != nil {
+				return err
+			}
+		}
+
 		// try to find node
-		foundLevel := list.Find(val, prev[:], next[:])
+		foundLevel := list.search(val, prev, next, finger)
 
 		// if not found or already marked for deletion
 		// return false
 		if isMarked || (foundLevel != -1 && canDelete(next[foundLevel], foundLevel)) {
 			// not already marked
 			if !isMarked {
+				// expired items are already gone
+				if list.expired(next[foundLevel]) {
+					list.expire(next[foundLevel])
+					return ErrNotFound
+				}
+
 				// get node
 				nodeToDelete = next[foundLevel]
 				topLevel = nodeToDelete.topLevel
@@ -381,19 +518,38 @@ func (list *Skiplist) Remove(val SkiplistItem) bool {
 				nodeToDelete.mux.Lock()
 
 				// did some other routine
-				// mark it first?
-				if nodeToDelete.marked {
+				// remove it first?
+				if nodeToDelete.loadMarked() || nodeToDelete.tombstoned() {
 					// yes, unlock and abort
 					nodeToDelete.mux.Unlock()
-					return false
+					return ErrConcurrentRemoval
+				}
+
+				// part of a pending batch, wait for it
+				if nodeToDelete.loadOwner() != nil {
+					nodeToDelete.mux.Unlock()
+					list.await(nodeToDelete)
+					continue
 				}
 
 				// no mark it for deletion
-				nodeToDelete.marked = true
+				if !list.retire(nodeToDelete) {
+					// kept for snapshots, nothing to unlink
+					nodeToDelete.mux.Unlock()
+
+					list.lock.Lock()
+					list.nElements--
+					list.lock.Unlock()
+
+					return nil
+				}
 				isMarked = true
-			}
 
-			// now locked
+				// marked, no one else changes it anymore, holding
+				// the lock while retrying could block a routine
+				// unlinking the nodes this one is waiting for
+				nodeToDelete.mux.Unlock()
+			}
 
 			highestLocked := -1
 			var pred, succ *skiplistNode
@@ -410,7 +566,7 @@ func (list *Skiplist) Remove(val SkiplistItem) bool {
 					highestLocked = level
 					prevPred = pred
 				}
-				valid = !pred.marked && pred.next[level] == succ
+				valid = !pred.loadMarked() && pred.loadNext(level) == succ
 			}
 
 			// can't delete try again
@@ -429,10 +585,15 @@ func (list *Skiplist) Remove(val SkiplistItem) bool {
 			}
 			// actually delete node
 			for level := topLevel; level >= 0; level-- {
-				prev[level].next[level] = nodeToDelete.next[level]
+				prev[level].storeNext(level, nodeToDelete.loadNext(level))
 			}
 
-			nodeToDelete.mux.Unlock()
+			// backward link
+			if succ := nodeToDelete.loadNext(0); succ != nil {
+				succ.storePrev(prev[0])
+			} else {
+				list.storeTail(prev[0])
+			}
 
 			// cleanup and unlock
 			prevPred = nil
@@ -448,17 +609,21 @@ func (list *Skiplist) Remove(val SkiplistItem) bool {
 			list.nElements--
 			list.lock.Unlock()
 
-			return true
+			return nil
 		}
 
-		return false
+		// being removed by some other routine
+		if foundLevel != -1 && next[foundLevel].loadMarked() {
+			return ErrConcurrentRemoval
+		}
+		return ErrNotFound
 
 	}
 }
 
 // helper
 func canDelete(candidate *skiplistNode, foundLevel int) bool {
-	return candidate.fullyLinked && candidate.topLevel == foundLevel && !candidate.marked
+	return candidate.loadFullyLinked() && candidate.topLevel == foundLevel && !candidate.loadMarked() && !candidate.tombstoned()
 }
 
 /*Union Merge two Skiplist sets into a new Skiplist, keeping the previous two intact.
@@ -466,19 +631,31 @@ The new Skiplist parameters will define the structure of the new Skiplist,
 meaning that the top levels of each node will be generated again
 O(N),Not threadsafe */
 func (list *Skiplist) Union(skipa, skipb *Skiplist) *Skiplist {
+	merged, _ := list.UnionWith(skipa, skipb, nil)
+	return merged
+}
+
+/*UnionWith Union, but an item in both Skiplists is replaced by
+resolve(item of skipa, item of skipb), which must equal both, and lives
+as long as the longest lived of the two. A nil resolve keeps the item of skipb.
+Returns the new Skiplist and how many items came from each side or were resolved.
+O(N),Not threadsafe */
+func (list *Skiplist) UnionWith(skipa, skipb *Skiplist, resolve Resolver) (*Skiplist, MergeStats) {
 
 	// can't have less max levels than its current levels
 	list.maxLevels = max(list.maxLevels, max(skipa.maxLevels, skipb.maxLevels))
 
 	// add head node
 	list.head = new(skiplistNode)
-	list.head.fullyLinked = true
+	list.head.storeFullyLinked(true)
 
 	// reset elements
 	list.nElements = 0
+	list.moved()
 
-	union(list, skipa, skipb, true)
-	return list
+	var stats MergeStats
+	union(list, skipa, skipb, true, resolve, &stats)
+	return list, stats
 }
 
 /*UnionSimple Merge two Skiplist sets into a new Skiplist, keeping the previous two intact.
@@ -510,7 +687,7 @@ func UnionSimple(skipa, skipb *Skiplist) *Skiplist {
 
 	// add head node
 	list.head = new(skiplistNode)
-	list.head.fullyLinked = true
+	list.head.storeFullyLinked(true)
 
 	// readjust max levels to make union possible
 	list.maxLevels = max(skipa.nLevels, list.maxLevels) // can't have less levels than its current
@@ -519,13 +696,14 @@ func UnionSimple(skipa, skipb *Skiplist) *Skiplist {
 	// reset elements
 	list.nElements = 0
 
-	union(list, skipa, skipb, false)
+	union(list, skipa, skipb, false, nil, &MergeStats{})
 	return list
 
 }
 
-/* actual implementation */
-func union(list, skipa, skipb *Skiplist, newProb bool) *Skiplist {
+/* actual implementation, items in both lists go through resolve
+if not nil and every item added is counted in stats */
+func union(list, skipa, skipb *Skiplist, newProb bool, resolve Resolver, stats *MergeStats) *Skiplist {
 
 	if newProb {
 		list.nLevels = 0
@@ -546,10 +724,10 @@ func union(list, skipa, skipb *Skiplist, newProb bool) *Skiplist {
 
 	// skip head nodes in both origins
 	if skipa.head != nil {
-		aptr = skipa.head.next[0]
+		aptr = skipa.head.loadNext(0)
 	}
 	if skipb.head != nil {
-		bptr = skipb.head.next[0]
+		bptr = skipb.head.loadNext(0)
 	}
 
 	/* last level contains all elements sorted
@@ -560,40 +738,71 @@ func union(list, skipa, skipb *Skiplist, newProb bool) *Skiplist {
 	/* merge */
 	for !(aptr == nil && bptr == nil) {
 
+		/* skip expired and removed elements */
+		if aptr != nil && !skipa.isLive(aptr) {
+			aptr = aptr.loadNext(0)
+			continue
+		} else if bptr != nil && !skipb.isLive(bptr) {
+			bptr = bptr.loadNext(0)
+			continue
+		}
+
 		/* skip same consecutive elements */
 		if prevElem != nil {
 			if aptr != nil && aptr.value.Equals(*prevElem) {
-				aptr = aptr.next[0]
+				aptr = aptr.loadNext(0)
 				continue
 			} else if bptr != nil && bptr.value.Equals(*prevElem) {
-				bptr = bptr.next[0]
+				bptr = bptr.loadNext(0)
 				continue
 			}
 		}
 
 		// create new node
 		newNode = new(skiplistNode)
-		newNode.fullyLinked = true
+		newNode.storeFullyLinked(true)
 
 		elementToAdd = nil
 
 		/* choose if element from first or second list will be added first */
-		if (aptr != nil && bptr != nil && aptr.value.Less(bptr.value)) || bptr == nil {
+		var conflict *skiplistNode
+		if aptr != nil && bptr != nil && aptr.value.Equals(bptr.value) {
+			// in both, keep the structure of the second
+			prevElem = &bptr.value
+			elementToAdd = bptr
+			conflict = aptr
+			// move both list pointers forward
+			aptr = aptr.loadNext(0)
+			bptr = bptr.loadNext(0)
+			stats.Conflicts++
+		} else if (aptr != nil && bptr != nil && aptr.value.Less(bptr.value)) || bptr == nil {
 			// keep prev for same check
 			prevElem = &aptr.value
 			elementToAdd = aptr
 			// move first list pointer forward
-			aptr = aptr.next[0]
+			aptr = aptr.loadNext(0)
+			stats.FromA++
 		} else {
 			// keep prev for same check
 			prevElem = &bptr.value
 			elementToAdd = bptr
 			// move second list pointer forward
-			bptr = bptr.next[0]
+			bptr = bptr.loadNext(0)
+			stats.FromB++
 		}
 
 		if elementToAdd != nil {
-			newNode.value = elementToAdd.value
+			newNode.value = elementToAdd.item()
+			newNode.expires = elementToAdd.expires
+
+			if conflict != nil && resolve != nil {
+				newNode.value = resolved("union", resolve, conflict.item(), newNode.value)
+
+				// expires with the last of the two
+				if conflict.expires == 0 || (newNode.expires != 0 && conflict.expires > newNode.expires) {
+					newNode.expires = conflict.expires
+				}
+			}
 
 			// keep previous structure or
 			//  generate new Skiplist of given probability
@@ -604,9 +813,10 @@ func union(list, skipa, skipb *Skiplist, newProb bool) *Skiplist {
 				list.nLevels = max(newNode.topLevel+1, list.nLevels)
 			}
 
+			newNode.storePrev(prevs[0])
 			for level := newNode.topLevel; level >= 0; level-- {
-				prevs[level].next[level] = newNode
-				prevs[level] = prevs[level].next[level]
+				prevs[level].storeNext(level, newNode)
+				prevs[level] = prevs[level].loadNext(level)
 			}
 
 			list.nElements++
@@ -615,6 +825,7 @@ func union(list, skipa, skipb *Skiplist, newProb bool) *Skiplist {
 
 	}
 
+	list.storeTail(prevs[0])
 	return list
 
 }
@@ -624,6 +835,16 @@ The new Skiplist parameters will define the structure of the new Skiplist,
 meaning that the top levels of insertion of each node will be generated again
 O(N),Not threadsafe */
 func (list *Skiplist) Intersection(skipa, skipb *Skiplist) *Skiplist {
+	intersected, _ := list.IntersectionWith(skipa, skipb, nil)
+	return intersected
+}
+
+/*IntersectionWith Intersection, but every item is replaced by
+combine(item of skipa, item of skipb), which must equal both.
+A nil combine keeps the item of skipa.
+Returns the new Skiplist and how many items were combined.
+O(N),Not threadsafe */
+func (list *Skiplist) IntersectionWith(skipa, skipb *Skiplist, combine Resolver) (*Skiplist, MergeStats) {
 
 	// max of two levels
 	list.nLevels = max(skipa.nLevels, skipb.nLevels)
@@ -632,13 +853,15 @@ func (list *Skiplist) Intersection(skipa, skipb *Skiplist) *Skiplist {
 
 	// add head node
 	list.head = new(skiplistNode)
-	list.head.fullyLinked = true
+	list.head.storeFullyLinked(true)
 
 	// reset elements
 	list.nElements = 0
+	list.moved()
 
-	intersection(list, skipa, skipb, true)
-	return list
+	var stats MergeStats
+	intersection(list, skipa, skipb, true, combine, &stats)
+	return list, stats
 
 }
 
@@ -669,19 +892,20 @@ func IntersectionSimple(skipa, skipb *Skiplist) *Skiplist {
 
 	// add head node
 	list.head = new(skiplistNode)
-	list.head.fullyLinked = true
+	list.head.storeFullyLinked(true)
 
 	// reset elements
 	list.nElements = 0
 
-	intersection(list, skipa, skipb, false)
+	intersection(list, skipa, skipb, false, nil, &MergeStats{})
 	return list
 
 }
 
-func intersection(intersected, skipa, skipb *Skiplist, newProb bool) *Skiplist {
+func intersection(intersected, skipa, skipb *Skiplist, newProb bool, combine Resolver, stats *MergeStats) *Skiplist {
 	/* merge two Skiplist sets into a new Skiplist, keeping the previous two intact.
 	If inherit_first is true, use probability and fastRandom of skipa else skipb.
+	Items go through combine if not nil and are counted in stats.
 	O(N),Not threadsafe */
 
 	// new Skiplist top level
@@ -693,7 +917,7 @@ func intersection(intersected, skipa, skipb *Skiplist, newProb bool) *Skiplist {
 
 	// add head node
 	intersected.head = new(skiplistNode)
-	intersected.head.fullyLinked = true
+	intersected.head.storeFullyLinked(true)
 
 	// reset elements
 	intersected.nElements = 0
@@ -711,10 +935,10 @@ func intersection(intersected, skipa, skipb *Skiplist, newProb bool) *Skiplist {
 
 	// skip head nodes in both origins
 	if skipa.head != nil {
-		aptr = skipa.head.next[0]
+		aptr = skipa.head.loadNext(0)
 	}
 	if skipb.head != nil {
-		bptr = skipb.head.next[0]
+		bptr = skipb.head.loadNext(0)
 	}
 
 	/* last level contains all elements sorted
@@ -724,13 +948,22 @@ func intersection(intersected, skipa, skipb *Skiplist, newProb bool) *Skiplist {
 	/* merge */
 	for aptr != nil && bptr != nil {
 
+		/* skip expired and removed elements */
+		if !skipa.isLive(aptr) {
+			aptr = aptr.loadNext(0)
+			continue
+		} else if !skipb.isLive(bptr) {
+			bptr = bptr.loadNext(0)
+			continue
+		}
+
 		/* skip same consecutive elements */
 		if prevElem != nil {
 			if aptr.value.Equals(*prevElem) {
-				aptr = aptr.next[0]
+				aptr = aptr.loadNext(0)
 				continue
 			} else if bptr.value.Equals(*prevElem) {
-				bptr = bptr.next[0]
+				bptr = bptr.loadNext(0)
 				continue
 			}
 		}
@@ -741,9 +974,19 @@ func intersection(intersected, skipa, skipb *Skiplist, newProb bool) *Skiplist {
 			prevElem = &aptr.value
 
 			newNode = new(skiplistNode)
-			newNode.fullyLinked = true
+			newNode.storeFullyLinked(true)
 
-			newNode.value = aptr.value
+			newNode.value = aptr.item()
+			if combine != nil {
+				newNode.value = resolved("intersection", combine, newNode.value, bptr.item())
+			}
+			stats.Conflicts++
+
+			// expires with the first of the two
+			newNode.expires = aptr.expires
+			if bptr.expires != 0 && (newNode.expires == 0 || bptr.expires < newNode.expires) {
+				newNode.expires = bptr.expires
+			}
 
 			// merge by level
 			// only levels which have the element in both lists
@@ -757,17 +1000,18 @@ func intersection(intersected, skipa, skipb *Skiplist, newProb bool) *Skiplist {
 			// update new total Skiplist maximum level
 			maxLevel = max(newNode.topLevel, maxLevel)
 
+			newNode.storePrev(prevs[0])
 			for level := newNode.topLevel; level >= 0; level-- {
-				prevs[level].next[level] = newNode
+				prevs[level].storeNext(level, newNode)
 
-				prevs[level] = prevs[level].next[level]
+				prevs[level] = prevs[level].loadNext(level)
 			}
 
 			intersected.nElements++
 
 			// move list pointers forward
-			aptr = aptr.next[0]
-			bptr = bptr.next[0]
+			aptr = aptr.loadNext(0)
+			bptr = bptr.loadNext(0)
 		} else if aptr.value.Less(bptr.value) {
 			// keep prev for same check
 			prevElem = &aptr.value
@@ -785,6 +1029,7 @@ func intersection(intersected, skipa, skipb *Skiplist, newProb bool) *Skiplist {
 
 	}
 	intersected.nLevels = maxLevel + 1
+	intersected.storeTail(prevs[0])
 	return intersected
 
 }
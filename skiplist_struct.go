@@ -1,6 +1,11 @@
 package goskiplist
 
-import "sync"
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+	"unsafe"
+)
 
 // SkiplistMaxLevel maximum levels allocated for each Skiplist
 // next pointer arrays are of constant size
@@ -10,24 +15,74 @@ const SkiplistMaxLevel = 30
 //for random function
 const MinProb = 0.01
 
+// skiplistNode links and flags are read without locking,
+// they are only accessed through the atomic helpers below
 type skiplistNode struct {
 	value       SkiplistItem
-	next        [SkiplistMaxLevel]*skiplistNode
-	marked      bool
-	fullyLinked bool
+	next        [SkiplistMaxLevel]unsafe.Pointer // *skiplistNode
+	prev        unsafe.Pointer                   // *skiplistNode, lowest level only
+	marked      int32
+	fullyLinked int32
 	mux         sync.Mutex
 	topLevel    int
+	expires     int64          // unix nanoseconds, 0 if it never expires
+	version     unsafe.Pointer // *nodeVersion, newest first, nil if loaded in bulk
+	owner       unsafe.Pointer // *batchTxn holding the node, nil if none
+}
+
+// nodeVersion item held by a node from a commit on,
+// deleted versions are tombstones kept for snapshots.
+// Never changed once stored, readers load it without locking
+type nodeVersion struct {
+	value   SkiplistItem
+	commit  int64
+	expires int64
+	deleted bool
+	txn     *batchTxn // pending batch, its commit applies if set
+	older   *nodeVersion
 }
 
 /*Skiplist : The Skiplist structure, must be initialised before use. */
 type Skiplist struct {
+	// 64-bit fields updated atomically come first, the first word of an
+	// allocated struct is the only one 64-bit aligned on 386 and ARM32
+	commitClock int64 // snapshots
+	generation  int64 // bumped whenever nodes are moved to or from other lists
+
 	nLevels    int
 	head       *skiplistNode
+	tail       unsafe.Pointer // *skiplistNode, last node of the lowest level, head if empty
 	nElements  int
 	prob       float64
 	maxLevels  int
 	lock       sync.RWMutex
 	fastRandom bool
+
+	// expiration
+	clock      Clock
+	ttl        time.Duration
+	onExpire   func(SkiplistItem)
+	stopReaper chan struct{}
+
+	// capacity
+	maxElements int
+	maxBytes    uintptr
+	evict       EvictionPolicy
+	onEvict     func(SkiplistItem)
+	evictMux    sync.Mutex
+
+	// change notification
+	subs        []*Subscription
+	nSubs       int32
+	subMux      sync.RWMutex
+	events      []queuedEvent // published, not delivered yet
+	nEvents     int32         // queued or being delivered
+	eventMux    sync.Mutex
+	dispatchMux sync.Mutex
+
+	// snapshots
+	snapshots []*Snapshot // by ascending commit
+	snapMux   sync.RWMutex
 }
 
 /*SkiplistItem type of inserted items,
@@ -36,3 +91,61 @@ type SkiplistItem interface {
 	Less(b SkiplistItem) bool
 	Equals(b SkiplistItem) bool
 }
+
+// loadNext successor on level
+func (node *skiplistNode) loadNext(level int) *skiplistNode {
+	return (*skiplistNode)(atomic.LoadPointer(&node.next[level]))
+}
+
+// storeNext link succ after node on level
+func (node *skiplistNode) storeNext(level int, succ *skiplistNode) {
+	atomic.StorePointer(&node.next[level], unsafe.Pointer(succ))
+}
+
+// loadPrev predecessor on the lowest level
+func (node *skiplistNode) loadPrev() *skiplistNode {
+	return (*skiplistNode)(atomic.LoadPointer(&node.prev))
+}
+
+// storePrev link pred before node on the lowest level
+func (node *skiplistNode) storePrev(pred *skiplistNode) {
+	atomic.StorePointer(&node.prev, unsafe.Pointer(pred))
+}
+
+// loadMarked true once the node is being unlinked
+func (node *skiplistNode) loadMarked() bool {
+	return atomic.LoadInt32(&node.marked) != 0
+}
+
+// storeMarked set whether the node is being unlinked
+func (node *skiplistNode) storeMarked(marked bool) {
+	atomic.StoreInt32(&node.marked, flag(marked))
+}
+
+// loadFullyLinked true once the node is linked on every level
+func (node *skiplistNode) loadFullyLinked() bool {
+	return atomic.LoadInt32(&node.fullyLinked) != 0
+}
+
+// storeFullyLinked set whether the node is linked on every level
+func (node *skiplistNode) storeFullyLinked(linked bool) {
+	atomic.StoreInt32(&node.fullyLinked, flag(linked))
+}
+
+// loadTail last node of the lowest level, head if empty
+func (list *Skiplist) loadTail() *skiplistNode {
+	return (*skiplistNode)(atomic.LoadPointer(&list.tail))
+}
+
+// storeTail set the last node of the lowest level
+func (list *Skiplist) storeTail(node *skiplistNode) {
+	atomic.StorePointer(&list.tail, unsafe.Pointer(node))
+}
+
+// flag int32 stored for a boolean
+func flag(b bool) int32 {
+	if b {
+		return 1
+	}
+	return 0
+}
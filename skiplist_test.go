@@ -1,8 +1,14 @@
 package goskiplist
 
 import (
+	"bytes"
+	"context"
+	"errors"
 	"fmt"
+	"math"
 	"math/rand"
+	"os"
+	"strings"
 	"sync"
 	"testing"
 	"time"
@@ -31,17 +37,7 @@ func evalSort(arr []SkiplistItem) bool {
 }
 
 func debug(head *Skiplist) {
-	for level := head.nLevels - 1; level >= 0; level-- {
-		listHead := head.head
-
-		for listHead != nil {
-			fmt.Print(listHead.value, " ")
-			listHead = listHead.next[level]
-		}
-
-		fmt.Println("nil")
-	}
-
+	head.WriteASCII(os.Stdout)
 }
 
 /* parallel inserters and removers */
@@ -247,6 +243,41 @@ func TestRandOperation(t *testing.T) {
 
 }
 
+func TestCoinTosses(t *testing.T) {
+	fmt.Println("---------------------------------------")
+	fmt.Println("Level cap")
+	fmt.Println("----------------------------------------")
+
+	rand.Seed(time.Now().UTC().UnixNano())
+
+	const draws = 100000
+	for _, fast := range []bool{FAST, VARIABLE} {
+		for _, maxLevels := range []int{1, 3, SkiplistMaxLevel} {
+			capped := 0
+			for index := 0; index < draws; index++ {
+				level := coinTosses(0.5, maxLevels, fast)
+				if level < 1 || level > maxLevels {
+					t.Fatalf("level %d drawn with a cap of %d", level, maxLevels)
+				}
+				if level == maxLevels {
+					capped++
+				}
+			}
+
+			// draws above the cap land on it, a quarter of them for 3 levels
+			if maxLevels == 1 && capped != draws {
+				t.Fatalf("%d of %d draws on a single level", capped, draws)
+			}
+			if maxLevels == 3 && (capped < draws/4-2000 || capped > draws/4+2000) {
+				t.Fatalf("%d of %d draws on the top of 3 levels, expected about %d", capped, draws, draws/4)
+			}
+		}
+	}
+
+	fmt.Println("OK!")
+	fmt.Println("----------------------------------------")
+}
+
 func TestConcurrentInsertAndOrder(t *testing.T) {
 
 	fmt.Println("--------------------------------------")
@@ -614,63 +645,2442 @@ func TestIntersect(t *testing.T) {
 	fmt.Println("----------------------------------------")
 }
 
-func BenchmarkInsert(b *testing.B) {
+func TestValidate(t *testing.T) {
+	fmt.Println("---------------------------------------")
+	fmt.Println("Structural validation after operations")
+	fmt.Println("----------------------------------------")
+
 	rand.Seed(time.Now().UTC().UnixNano())
 
 	var head = New(0.5, 30, FAST)
+	var head2 = New(0.25, 16, VARIABLE)
 
-	b.ResetTimer()
+	if err := head.Validate(); err != nil {
+		t.Errorf("Empty Skiplist should be valid: %v", err)
+	}
 
-	//var wg sync.WaitGroup
+	for index := 0; index < dataAmount; index++ {
+		head.Insert(Int(rand.Intn(dataAmount)))
+		head2.Insert(Int(2 * index))
+	}
 
-	for index := 0; index < b.N; index++ {
-		if !head.Insert(Int(index)) {
-			b.Errorf("Could not insert item %d", index)
+	if err := head.Validate(); err != nil {
+		t.Errorf("Skiplist invalid after inserts: %v", err)
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(nRoutinesToUse)
+	for index := 0; index < nRoutinesToUse; index++ {
+		go func(v int) {
+			defer wg.Done()
+			for i := 0; i < dataAmount/nRoutinesToUse; i++ {
+				if v%2 == 0 {
+					head.Insert(Int(rand.Intn(dataAmount)))
+				} else {
+					head.Remove(Int(rand.Intn(dataAmount)))
+				}
+			}
+		}(index)
+	}
+	wg.Wait()
+
+	if err := head.Validate(); err != nil {
+		t.Errorf("Skiplist invalid after concurrent inserts and removes: %v", err)
+	}
+
+	checks := map[string]*Skiplist{
+		"Union":              New(0.5, 30, FAST).Union(head, head2),
+		"UnionSimple":        UnionSimple(head, head2),
+		"Intersection":       New(0.5, 30, FAST).Intersection(head, head2),
+		"IntersectionSimple": IntersectionSimple(head, head2),
+	}
+
+	for name, list := range checks {
+		if err := list.Validate(); err != nil {
+			t.Errorf("%s result invalid: %v", name, err)
 		}
 	}
 
+	// corrupt the structure, must be detected
+	first := head.head.loadNext(0)
+	first.storeMarked(true)
+	if head.Validate() == nil {
+		t.Errorf("Marked reachable node not detected")
+	}
+	first.storeMarked(false)
+
+	head.nElements++
+	if head.Validate() == nil {
+		t.Errorf("Wrong element count not detected")
+	}
+	head.nElements--
+
+	first.topLevel++
+	if head.Validate() == nil {
+		t.Errorf("Wrong topLevel not detected")
+	}
+	first.topLevel--
+
+	if err := head.Validate(); err != nil {
+		t.Errorf("Skiplist should be valid after restoring it: %v", err)
+	}
+
+	fmt.Println("OK!")
+	fmt.Println("----------------------------------------")
 }
 
-func BenchmarkDelete(b *testing.B) {
+func TestStats(t *testing.T) {
+	fmt.Println("---------------------------------------")
+	fmt.Println("Level distribution and shape statistics")
+	fmt.Println("----------------------------------------")
+
+	rand.Seed(time.Now().UTC().UnixNano())
+
+	configs := []struct {
+		prob       float64
+		maxLevels  int
+		fastRandom bool
+	}{
+		{0.5, 30, FAST},
+		{0.25, 30, VARIABLE},
+		{0.75, 30, VARIABLE},
+	}
+
+	for _, config := range configs {
+		var head = New(config.prob, config.maxLevels, config.fastRandom)
+		for index := 0; index < 10*dataAmount; index++ {
+			head.Insert(Int(index))
+		}
+
+		stats := head.Stats()
+		fmt.Printf("prob %.2f fast %v: empirical %.3f, levels %v, search path %.1f expected %.1f\n",
+			stats.ConfiguredProb, config.fastRandom, stats.EmpiricalProb, stats.LevelCounts,
+			stats.SampledSearchPath, stats.ExpectedSearchPath)
+
+		if stats.Elements != head.Len() || stats.LevelCounts[0] != head.Len() {
+			t.Errorf("Stats report %d elements but Skiplist contains %d", stats.Elements, head.Len())
+		}
+
+		if math.Abs(stats.EmpiricalProb-stats.ConfiguredProb) > 0.05 {
+			t.Errorf("Empirical probability %.3f too far from configured %.3f", stats.EmpiricalProb, stats.ConfiguredProb)
+		}
+
+		if stats.SampledSearchPath <= 0 || stats.SampledSearchPath > 2*stats.ExpectedSearchPath {
+			t.Errorf("Sampled search path %.1f far from expected %.1f", stats.SampledSearchPath, stats.ExpectedSearchPath)
+		}
+
+		if stats.UsedPointers+stats.WastedPointers != (stats.Elements+1)*SkiplistMaxLevel {
+			t.Errorf("Used and wasted pointers do not add up to the allocated slots")
+		}
+	}
+
+	// levels never exceed maxLevels
+	var short = New(0.9, 4, VARIABLE)
+	for index := 0; index < dataAmount; index++ {
+		short.Insert(Int(index))
+	}
+	if levels := short.Stats().Levels; levels > 4 {
+		t.Errorf("Skiplist with 4 max levels uses %d levels", levels)
+	}
+
+	fmt.Println("OK!")
+	fmt.Println("----------------------------------------")
+}
+
+func TestRender(t *testing.T) {
+	fmt.Println("---------------------------------------")
+	fmt.Println("DOT and ASCII rendering")
+	fmt.Println("----------------------------------------")
+
+	var head = New(0.5, 30, FAST)
+	for index := 0; index < 10; index++ {
+		head.Insert(Int(index))
+	}
+
+	var ascii bytes.Buffer
+	if err := head.WriteASCII(&ascii); err != nil {
+		t.Errorf("Could not render ASCII: %v", err)
+	}
+	fmt.Print(ascii.String())
+
+	rows := strings.Split(strings.TrimSpace(ascii.String()), "\n")
+	if len(rows) != head.Height() {
+		t.Errorf("ASCII diagram should have %d rows but has %d", head.Height(), len(rows))
+	}
+	if !strings.HasSuffix(rows[len(rows)-1], "head--0--1--2--3--4--5--6--7--8--9--nil") {
+		t.Errorf("Lowest level rendered as %q", rows[len(rows)-1])
+	}
+
+	head.head.loadNext(0).storeMarked(true)
+	var dot bytes.Buffer
+	if err := head.WriteDOT(&dot); err != nil {
+		t.Errorf("Could not render DOT: %v", err)
+	}
+	head.head.loadNext(0).storeMarked(false)
+
+	out := dot.String()
+	if !strings.HasPrefix(out, "digraph skiplist {") || !strings.HasSuffix(out, "}\n") {
+		t.Errorf("DOT output is not a digraph")
+	}
+	if !strings.Contains(out, "head:l0 -> n0:l0;") || !strings.Contains(out, "n9:l0 -> nil;") {
+		t.Errorf("DOT output misses lowest level links")
+	}
+	if !strings.Contains(out, "color=red") {
+		t.Errorf("DOT output does not highlight marked node")
+	}
+
+	fmt.Println("OK!")
+	fmt.Println("----------------------------------------")
+}
+
+func TestTrace(t *testing.T) {
+	fmt.Println("---------------------------------------")
+	fmt.Println("Search path tracing")
+	fmt.Println("----------------------------------------")
+
 	rand.Seed(time.Now().UTC().UnixNano())
 
 	var head = New(0.5, 30, FAST)
+	for index := 0; index < dataAmount; index += 2 {
+		head.Insert(Int(index))
+	}
 
-	for index := 0; index < b.N; index++ {
-		if !head.Insert(Int(index)) {
-			b.Errorf("Could not insert item %d", index)
+	for _, val := range []Int{0, dataAmount / 2, dataAmount/2 + 1, dataAmount - 2, dataAmount} {
+		trace := head.Trace(val)
+		found := head.Contains(val)
+
+		if found != (trace.FoundLevel != -1) {
+			t.Errorf("Trace of %d reports found level %d but Contains returns %v", val, trace.FoundLevel, found)
+		}
+
+		if trace.LevelDrops != head.Height() {
+			t.Errorf("Trace of %d dropped %d levels, Skiplist has %d", val, trace.LevelDrops, head.Height())
+		}
+
+		if trace.PathLength() != len(trace.Steps)-btoi(found) {
+			t.Errorf("Trace of %d has %d steps but path length %d", val, len(trace.Steps), trace.PathLength())
+		}
+
+		// every advance is a failed or successful Less call
+		if trace.LessCalls < trace.Advances || trace.LessCalls > trace.Advances+trace.LevelDrops {
+			t.Errorf("Trace of %d made %d Less calls for %d advances", val, trace.LessCalls, trace.Advances)
+		}
+
+		var prev SkiplistItem
+		for _, step := range trace.Steps {
+			if step.Action == TraceAdvance {
+				if !step.Item.Less(val) || (prev != nil && !prev.Less(step.Item)) {
+					t.Errorf("Trace of %d advanced to %v out of order", val, step.Item)
+				}
+				prev = step.Item
+			}
+		}
+
+		last := trace.Steps[len(trace.Steps)-1]
+		if last.Level != 0 || last.Action != TraceDrop {
+			t.Errorf("Trace of %d should end by dropping below level 0", val)
 		}
 	}
 
-	b.ResetTimer()
+	fmt.Println("OK!")
+	fmt.Println("----------------------------------------")
+}
 
-	for index := 0; index < b.N; index++ {
-		if !head.Remove(Int(index)) {
-			b.Errorf("Could not insert item %d", index)
+func btoi(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+func TestFromSorted(t *testing.T) {
+	fmt.Println("---------------------------------------")
+	fmt.Println("Bulk load from sorted input")
+	fmt.Println("----------------------------------------")
+
+	rand.Seed(time.Now().UTC().UnixNano())
+
+	items := make([]SkiplistItem, dataAmount)
+	for index := range items {
+		items[index] = Int(2 * index)
+	}
+
+	head, err := New(0.5, 30, FAST).FromSorted(items)
+	if err != nil {
+		t.Errorf("Could not load sorted items: %v", err)
+	}
+
+	if err := head.Validate(); err != nil {
+		t.Errorf("Bulk loaded Skiplist invalid: %v", err)
+	}
+
+	if head.Len() != dataAmount {
+		t.Errorf("Skiplist should contain %d items but contains %d", dataAmount, head.Len())
+	}
+
+	for index := 0; index < 2*dataAmount; index++ {
+		if head.Contains(Int(index)) != (index%2 == 0) {
+			t.Errorf("Contains wrong for %d after bulk load", index)
 		}
 	}
 
+	// still usable concurrently
+	if !head.Insert(Int(1)) || !head.Remove(Int(0)) {
+		t.Errorf("Could not modify bulk loaded Skiplist")
+	}
+
+	// iterator variant over another list
+	copied, err := New(0.25, 16, VARIABLE).FromIterator(&sliceIterator{items: head.ToSortedArray()})
+	if err != nil {
+		t.Errorf("Could not load from iterator: %v", err)
+	}
+	if copied.Len() != head.Len() || copied.Validate() != nil {
+		t.Errorf("Skiplist loaded from iterator differs from source")
+	}
+
+	// bad input leaves the list untouched
+	unsorted := []SkiplistItem{Int(1), Int(3), Int(2)}
+	if _, err := copied.FromSorted(unsorted); err == nil {
+		t.Errorf("Unsorted input not detected")
+	}
+	duplicates := []SkiplistItem{Int(1), Int(2), Int(2)}
+	if _, err := copied.FromSorted(duplicates); err == nil {
+		t.Errorf("Duplicate input not detected")
+	}
+	if copied.Len() != head.Len() || copied.Validate() != nil {
+		t.Errorf("Skiplist modified by rejected bulk load")
+	}
+
+	fmt.Println("OK!")
+	fmt.Println("----------------------------------------")
 }
 
-func BenchmarkSearch(b *testing.B) {
+func TestInsertRemoveMany(t *testing.T) {
+	fmt.Println("---------------------------------------")
+	fmt.Println("Batched insert and remove")
+	fmt.Println("----------------------------------------")
+
 	rand.Seed(time.Now().UTC().UnixNano())
 
 	var head = New(0.5, 30, FAST)
 
-	for index := 0; index < b.N; index++ {
-		if !head.Insert(Int(index)) {
-			b.Errorf("Could not insert item %d", index)
+	// shuffled batch with every item twice
+	items := make([]SkiplistItem, 0, 2*dataAmount)
+	for index := 0; index < dataAmount; index++ {
+		items = append(items, Int(index), Int(index))
+	}
+	rand.Shuffle(len(items), func(i, j int) { items[i], items[j] = items[j], items[i] })
+
+	results := head.InsertMany(items)
+
+	inserted := make(map[Int]int)
+	for index, ok := range results {
+		if ok {
+			inserted[items[index].(Int)]++
+		}
+	}
+	for index := 0; index < dataAmount; index++ {
+		if inserted[Int(index)] != 1 {
+			t.Errorf("Item %d reported inserted %d times", index, inserted[Int(index)])
 		}
 	}
 
-	b.ResetTimer()
+	if head.Len() != dataAmount || head.Validate() != nil {
+		t.Errorf("Skiplist should be valid with %d items but has %d", dataAmount, head.Len())
+	}
 
-	for index := 0; index < b.N; index++ {
-		if !head.Contains(Int(index)) {
-			b.Errorf("Could not insert item %d", index)
+	// concurrent overlapping batches
+	var wg sync.WaitGroup
+	var mux sync.Mutex
+	expected := dataAmount
+	wg.Add(nRoutinesToUse)
+	for index := 0; index < nRoutinesToUse; index++ {
+		go func(v int) {
+			defer wg.Done()
+			batch := make([]SkiplistItem, 0, 2*dataAmount/nRoutinesToUse)
+			for i := 0; i < 2*dataAmount/nRoutinesToUse; i++ {
+				batch = append(batch, Int(rand.Intn(2*dataAmount)))
+			}
+
+			var results []bool
+			change := 1
+			if v%2 == 0 {
+				results = head.InsertMany(batch)
+			} else {
+				results = head.RemoveMany(batch)
+				change = -1
+			}
+
+			mux.Lock()
+			for _, ok := range results {
+				if ok {
+					expected += change
+				}
+			}
+			mux.Unlock()
+		}(index)
+	}
+	wg.Wait()
+
+	if head.Len() != expected {
+		t.Errorf("Skiplist should contain %d items but contains %d", expected, head.Len())
+	}
+	if err := head.Validate(); err != nil {
+		t.Errorf("Skiplist invalid after concurrent batches: %v", err)
+	}
+
+	fmt.Println("OK!")
+	fmt.Println("----------------------------------------")
+}
+
+func TestRemoveRange(t *testing.T) {
+	fmt.Println("---------------------------------------")
+	fmt.Println("Range and predicate removal")
+	fmt.Println("----------------------------------------")
+
+	rand.Seed(time.Now().UTC().UnixNano())
+
+	var head = New(0.5, 30, FAST)
+	for index := 0; index < dataAmount; index++ {
+		head.Insert(Int(index))
+	}
+
+	if removed := head.RemoveRange(Int(100), Int(200)); removed != 100 {
+		t.Errorf("Should remove 100 items but removed %d", removed)
+	}
+	if !head.Contains(Int(99)) || head.Contains(Int(100)) || head.Contains(Int(199)) || !head.Contains(Int(200)) {
+		t.Errorf("Range bounds not respected")
+	}
+	if removed := head.RemoveRange(Int(100), Int(200)); removed != 0 {
+		t.Errorf("Empty range removed %d items", removed)
+	}
+
+	if removed := head.RemoveIf(func(item SkiplistItem) bool { return item.(Int)%2 == 1 }); removed != (dataAmount-100)/2 {
+		t.Errorf("Should remove %d odd items but removed %d", (dataAmount-100)/2, removed)
+	}
+
+	if head.Len() != (dataAmount-100)/2 {
+		t.Errorf("Skiplist should contain %d items but contains %d", (dataAmount-100)/2, head.Len())
+	}
+	if err := head.Validate(); err != nil {
+		t.Errorf("Skiplist invalid after range removal: %v", err)
+	}
+
+	// concurrent ranges with single removes and inserts
+	for index := 0; index < dataAmount; index++ {
+		head.Insert(Int(index))
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(nRoutinesToUse)
+	for index := 0; index < nRoutinesToUse; index++ {
+		go func(v int) {
+			defer wg.Done()
+			lo := rand.Intn(dataAmount)
+			switch v % 3 {
+			case 0:
+				head.RemoveRange(Int(lo), Int(lo+dataAmount/10))
+			case 1:
+				head.Remove(Int(lo))
+			case 2:
+				head.Insert(Int(lo))
+			}
+		}(index)
+	}
+	wg.Wait()
+
+	if err := head.Validate(); err != nil {
+		t.Errorf("Skiplist invalid after concurrent range removal: %v", err)
+	}
+
+	if removed := head.RemoveRange(nil, nil); head.Len() != 0 || removed == 0 {
+		t.Errorf("Open range should empty the Skiplist, %d items left", head.Len())
+	}
+
+	fmt.Println("OK!")
+	fmt.Println("----------------------------------------")
+}
+
+func TestPop(t *testing.T) {
+	fmt.Println("---------------------------------------")
+	fmt.Println("PopMin and PopMax")
+	fmt.Println("----------------------------------------")
+
+	rand.Seed(time.Now().UTC().UnixNano())
+
+	var head = New(0.5, 30, FAST)
+	for index := 0; index < dataAmount; index++ {
+		head.Insert(Int(index))
+	}
+
+	for index := 0; index < 10; index++ {
+		if item := head.PopMin(); item != Int(index) {
+			t.Errorf("PopMin should return %d but returned %v", index, item)
+		}
+		if item := head.PopMax(); item != Int(dataAmount-1-index) {
+			t.Errorf("PopMax should return %d but returned %v", dataAmount-1-index, item)
+		}
+	}
+
+	if head.Len() != dataAmount-20 || head.Validate() != nil {
+		t.Errorf("Skiplist should be valid with %d items but has %d", dataAmount-20, head.Len())
+	}
+
+	// concurrent poppers, every item popped exactly once
+	popped := make([]int, dataAmount)
+	var wg sync.WaitGroup
+	var mux sync.Mutex
+	wg.Add(nRoutinesToUse)
+	for index := 0; index < nRoutinesToUse; index++ {
+		go func(v int) {
+			defer wg.Done()
+			for {
+				var item SkiplistItem
+				switch v % 3 {
+				case 0:
+					item = head.PopMin()
+				case 1:
+					item = head.PopMinRelaxed(nRoutinesToUse)
+				case 2:
+					item = head.PopMax()
+				}
+				if item == nil {
+					return
+				}
+				mux.Lock()
+				popped[item.(Int)]++
+				mux.Unlock()
+			}
+		}(index)
+	}
+	wg.Wait()
+
+	for index := 10; index < dataAmount-10; index++ {
+		if popped[index] != 1 {
+			t.Errorf("Item %d popped %d times", index, popped[index])
 		}
 	}
 
+	if head.Len() != 0 || head.PopMin() != nil || head.PopMax() != nil {
+		t.Errorf("Skiplist should be empty")
+	}
+	if err := head.Validate(); err != nil {
+		t.Errorf("Skiplist invalid after concurrent pops: %v", err)
+	}
+
+	fmt.Println("OK!")
+	fmt.Println("----------------------------------------")
+}
+
+func TestIterators(t *testing.T) {
+	fmt.Println("---------------------------------------")
+	fmt.Println("Ascending and descending iteration")
+	fmt.Println("----------------------------------------")
+
+	rand.Seed(time.Now().UTC().UnixNano())
+
+	var head = New(0.5, 30, FAST)
+
+	if head.Min() != nil || head.Max() != nil || head.ReverseIterator().Next() {
+		t.Errorf("Empty Skiplist should have no items")
+	}
+
+	for _, index := range rand.Perm(dataAmount) {
+		head.Insert(Int(index))
+	}
+	head.RemoveRange(Int(dataAmount-10), nil)
+	head.Remove(Int(0))
+
+	if head.Min() != Int(1) || head.Max() != Int(dataAmount-11) {
+		t.Errorf("Min and Max should be 1 and %d but are %v and %v", dataAmount-11, head.Min(), head.Max())
+	}
+
+	ascending := make([]SkiplistItem, 0, head.Len())
+	for it := head.Iterator(); it.Next(); {
+		ascending = append(ascending, it.Item())
+	}
+	descending := make([]SkiplistItem, 0, head.Len())
+	for it := head.ReverseIterator(); it.Next(); {
+		descending = append(descending, it.Item())
+	}
+
+	if len(ascending) != head.Len() || len(descending) != head.Len() {
+		t.Errorf("Iterators returned %d and %d items, Skiplist contains %d", len(ascending), len(descending), head.Len())
+	}
+	for index := range ascending {
+		if ascending[index] != descending[len(descending)-1-index] {
+			t.Errorf("Descending iteration is not the reverse of ascending at %d", index)
+			break
+		}
+	}
+	if !evalSort(ascending) {
+		t.Errorf("Items out of order")
+	}
+
+	if err := head.Validate(); err != nil {
+		t.Errorf("Skiplist invalid: %v", err)
+	}
+
+	fmt.Println("OK!")
+	fmt.Println("----------------------------------------")
+}
+
+// manually advanced clock
+type testClock struct {
+	mux sync.Mutex
+	now time.Time
+}
+
+func (clock *testClock) Now() time.Time {
+	defer clock.mux.Unlock()
+	clock.mux.Lock()
+	return clock.now
+}
+
+func (clock *testClock) Advance(d time.Duration) {
+	defer clock.mux.Unlock()
+	clock.mux.Lock()
+	clock.now = clock.now.Add(d)
+}
+
+func TestTTL(t *testing.T) {
+	fmt.Println("---------------------------------------")
+	fmt.Println("Time to live expiration")
+	fmt.Println("----------------------------------------")
+
+	rand.Seed(time.Now().UTC().UnixNano())
+
+	clock := &testClock{now: time.Unix(0, 0)}
+
+	var head = New(0.5, 30, FAST)
+	head.SetClock(clock)
+
+	var mux sync.Mutex
+	expired := make(map[Int]int)
+	head.OnExpire(func(item SkiplistItem) {
+		mux.Lock()
+		expired[item.(Int)]++
+		mux.Unlock()
+	})
+
+	// even items expire after a minute, odd ones never
+	head.SetDefaultTTL(time.Minute)
+	for index := 0; index < dataAmount; index += 2 {
+		head.Insert(Int(index))
+	}
+	head.SetDefaultTTL(0)
+	for index := 1; index < dataAmount; index += 2 {
+		head.Insert(Int(index))
+	}
+	// overrides the default
+	head.InsertWithTTL(Int(dataAmount), time.Hour)
+
+	clock.Advance(time.Minute)
+
+	for index := 0; index < dataAmount; index++ {
+		if head.Contains(Int(index)) != (index%2 == 1) {
+			t.Errorf("Item %d visibility wrong after expiration", index)
+		}
+		if (head.Get(Int(index)) != nil) != (index%2 == 1) {
+			t.Errorf("Get of item %d wrong after expiration", index)
+		}
+	}
+	if !head.Contains(Int(dataAmount)) {
+		t.Errorf("Item with longer ttl expired early")
+	}
+
+	for it := head.Iterator(); it.Next(); {
+		if it.Item().(Int)%2 == 0 && it.Item() != Int(dataAmount) {
+			t.Errorf("Iteration returned expired item %v", it.Item())
+		}
+	}
+	if head.Min() != Int(1) {
+		t.Errorf("Min should skip expired items but is %v", head.Min())
+	}
+
+	// expired items can be inserted again, removing the old one
+	if !head.Insert(Int(0)) || !head.Contains(Int(0)) || expired[0] != 1 {
+		t.Errorf("Could not insert again over expired item")
+	}
+	if head.Remove(Int(2)) || expired[2] != 1 {
+		t.Errorf("Removing an expired item should fail and reap it")
+	}
+
+	if reaped := head.Reap(); reaped != dataAmount/2-2 {
+		t.Errorf("Should reap %d items but reaped %d", dataAmount/2-2, reaped)
+	}
+	for index := 0; index < dataAmount; index += 2 {
+		if expired[Int(index)] != 1 {
+			t.Errorf("Expiry callback called %d times for %d", expired[Int(index)], index)
+		}
+	}
+
+	if head.Len() != dataAmount/2+2 {
+		t.Errorf("Skiplist should contain %d items but contains %d", dataAmount/2+2, head.Len())
+	}
+	if err := head.Validate(); err != nil {
+		t.Errorf("Skiplist invalid after reaping: %v", err)
+	}
+
+	// background reaper
+	reaped := make(chan SkiplistItem, 1)
+	head.OnExpire(func(item SkiplistItem) { reaped <- item })
+	head.StartReaper(time.Millisecond)
+	defer head.StopReaper()
+
+	clock.Advance(time.Hour)
+	select {
+	case item := <-reaped:
+		if item != Int(dataAmount) {
+			t.Errorf("Reaper expired %v instead of %d", item, dataAmount)
+		}
+	case <-time.After(5 * time.Second):
+		t.Errorf("Background reaper did not remove expired item")
+	}
+
+	fmt.Println("OK!")
+	fmt.Println("----------------------------------------")
+}
+
+func TestCapacity(t *testing.T) {
+	fmt.Println("---------------------------------------")
+	fmt.Println("Bounded capacity with eviction")
+	fmt.Println("----------------------------------------")
+
+	rand.Seed(time.Now().UTC().UnixNano())
+
+	// top 10 scores
+	var head = New(0.5, 30, FAST)
+	head.SetCapacity(10, 0)
+
+	var mux sync.Mutex
+	evicted := 0
+	head.OnEvict(func(item SkiplistItem) {
+		mux.Lock()
+		evicted++
+		mux.Unlock()
+	})
+
+	var wg sync.WaitGroup
+	wg.Add(nRoutinesToUse)
+	for index := 0; index < nRoutinesToUse; index++ {
+		go head.Inserter(index, &wg)
+	}
+	wg.Wait()
+
+	if head.Len() != 10 || evicted != dataAmount-10 {
+		t.Errorf("Skiplist should hold 10 items after %d evictions but holds %d after %d", dataAmount-10, head.Len(), evicted)
+	}
+	for index := dataAmount - 10; index < dataAmount; index++ {
+		if !head.Contains(Int(index)) {
+			t.Errorf("Top item %d evicted", index)
+		}
+	}
+
+	// bottom 10
+	head = New(0.5, 30, FAST)
+	head.SetEvictionPolicy(EvictMax)
+	head.SetCapacity(10, 0)
+	for _, index := range rand.Perm(dataAmount) {
+		head.Insert(Int(index))
+	}
+	if head.Max() != Int(9) || head.Len() != 10 {
+		t.Errorf("Skiplist should hold items 0 to 9 but holds %d items up to %v", head.Len(), head.Max())
+	}
+
+	// custom policy, byte limit
+	head = New(0.5, 30, FAST)
+	head.SetEvictionPolicy(func(list *Skiplist) SkiplistItem {
+		// evict the odd items first
+		for it := list.Iterator(); it.Next(); {
+			if it.Item().(Int)%2 == 1 && list.Remove(it.Item()) {
+				return it.Item()
+			}
+		}
+		return list.PopMin()
+	})
+	head.SetCapacity(0, estimatedBytes(5))
+	for index := 0; index < 10; index++ {
+		head.Insert(Int(index))
+	}
+	if head.Len() != 5 || head.Contains(Int(1)) || !head.Contains(Int(8)) {
+		t.Errorf("Byte limited Skiplist should hold the 5 even items but holds %v", head.ToSortedArray())
+	}
+
+	// shrinking evicts right away
+	head.SetCapacity(2, 0)
+	if head.Len() != 2 {
+		t.Errorf("Skiplist should shrink to 2 items but holds %d", head.Len())
+	}
+
+	fmt.Println("OK!")
+	fmt.Println("----------------------------------------")
+}
+
+func TestSubscribe(t *testing.T) {
+	fmt.Println("---------------------------------------")
+	fmt.Println("Change notification")
+	fmt.Println("----------------------------------------")
+
+	rand.Seed(time.Now().UTC().UnixNano())
+
+	var head = New(0.5, 30, FAST)
+
+	all := head.Subscribe(nil, nil, 0, OverflowBlock)
+	ranged := head.Subscribe(Int(100), Int(200), 4*dataAmount, OverflowBlock)
+	dropping := head.Subscribe(nil, nil, 1, OverflowDrop)
+
+	// consume every event, checking per item order
+	received := make(chan []Event)
+	go func() {
+		var events []Event
+		for event := range all.Events() {
+			events = append(events, event)
+		}
+		received <- events
+	}()
+
+	var wg sync.WaitGroup
+	wg.Add(nRoutinesToUse)
+	for index := 0; index < nRoutinesToUse; index++ {
+		go func(v int) {
+			defer wg.Done()
+			for i := 0; i < dataAmount/nRoutinesToUse; i++ {
+				item := Int(rand.Intn(dataAmount / 2))
+				if v%2 == 0 {
+					head.Insert(item)
+				} else {
+					head.Remove(item)
+				}
+			}
+		}(index)
+	}
+	wg.Wait()
+	head.RemoveRange(nil, nil)
+
+	all.Close()
+	ranged.Close()
+	dropping.Close()
+
+	events := <-received
+
+	// every item alternates between inserted and removed
+	present := make(map[Int]bool)
+	for _, event := range events {
+		item := event.Item.(Int)
+		if (event.Op == EventInsert) == present[item] {
+			t.Errorf("Event %v of %d out of order", event.Op, item)
+		}
+		present[item] = event.Op == EventInsert
+	}
+	for item, in := range present {
+		if in {
+			t.Errorf("No remove event for %d", item)
+		}
+	}
+
+	rangedEvents := 0
+	for event := range ranged.Events() {
+		if item := event.Item.(Int); item < 100 || item >= 200 {
+			t.Errorf("Event of %d outside subscribed range", item)
+		}
+		rangedEvents++
+	}
+	if rangedEvents == 0 {
+		t.Errorf("No events in subscribed range")
+	}
+
+	if dropping.Dropped() == 0 {
+		t.Errorf("Full subscriber should drop events")
+	}
+
+	// coalescing keeps the latest pending event of each item
+	coalescing := head.Subscribe(nil, nil, 1, OverflowCoalesce)
+	head.Insert(Int(1))
+	head.Remove(Int(1))
+	head.Insert(Int(1))
+	head.Insert(Int(2))
+
+	first, second, third := <-coalescing.Events(), <-coalescing.Events(), <-coalescing.Events()
+	if first.Item != Int(1) || first.Op != EventInsert || second.Item != Int(1) || second.Op != EventInsert ||
+		third.Item != Int(2) {
+		t.Errorf("Coalesced events wrong: %v %v %v", first, second, third)
+	}
+	coalescing.Close()
+
+	fmt.Println("OK!")
+	fmt.Println("----------------------------------------")
+}
+
+func TestSnapshot(t *testing.T) {
+	fmt.Println("---------------------------------------")
+	fmt.Println("Snapshot isolation")
+	fmt.Println("----------------------------------------")
+
+	var head = New(0.5, 30, FAST)
+	for i := 0; i < 100; i++ {
+		head.Insert(Int(i))
+	}
+
+	snap := head.Snapshot()
+
+	// every kind of removal and insert after the snapshot
+	for i := 0; i < 50; i += 2 {
+		head.Remove(Int(i))
+	}
+	head.RemoveRange(Int(90), nil)
+	head.PopMin()
+	head.PopMax()
+	for i := 100; i < 150; i++ {
+		head.Insert(Int(i))
+	}
+	head.Insert(Int(2))
+
+	if err := head.Validate(); err != nil {
+		t.Errorf("Invalid with tombstones: %v", err)
+	}
+
+	arr := snap.ToSortedArray()
+	if len(arr) != 100 {
+		t.Errorf("Snapshot has %d items, should have 100", len(arr))
+	}
+	for index, item := range arr {
+		if item != Int(index) {
+			t.Errorf("Snapshot item %d is %v", index, item)
+			break
+		}
+	}
+	if !snap.Contains(Int(4)) || !snap.Contains(Int(95)) || snap.Contains(Int(120)) {
+		t.Errorf("Snapshot lookups see later changes")
+	}
+	if head.Contains(Int(4)) || head.Contains(Int(95)) || !head.Contains(Int(2)) || !head.Contains(Int(120)) {
+		t.Errorf("Skiplist lookups see removed items")
+	}
+
+	snap2 := head.Snapshot()
+	snap2Len := head.Len()
+	head.Remove(Int(2))
+	head.Remove(Int(120))
+
+	if !snap.Contains(Int(2)) || !snap2.Contains(Int(2)) || !snap2.Contains(Int(120)) || head.Contains(Int(2)) {
+		t.Errorf("Snapshots do not keep removed items")
+	}
+
+	snap.Release()
+	if err := head.Validate(); err != nil {
+		t.Errorf("Invalid after release: %v", err)
+	}
+	if !snap2.Contains(Int(120)) || snap2.Contains(Int(4)) || len(snap2.ToSortedArray()) != snap2Len {
+		t.Errorf("Release broke the remaining snapshot")
+	}
+
+	// no tombstones left once every snapshot is gone
+	snap2.Release()
+	if err := head.Validate(); err != nil {
+		t.Errorf("Invalid after release: %v", err)
+	}
+	linked := 0
+	for curr := head.head.loadNext(0); curr != nil; curr = curr.loadNext(0) {
+		linked++
+	}
+	if linked != head.Len() {
+		t.Errorf("%d items linked but Len is %d", linked, head.Len())
+	}
+
+	// frozen view under concurrent writers
+	rand.Seed(time.Now().UTC().UnixNano())
+	const writers = 4
+	stop := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(writers)
+	for index := 0; index < writers; index++ {
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-stop:
+					return
+				default:
+				}
+				item := Int(rand.Intn(1000))
+				if rand.Intn(2) == 0 {
+					head.Insert(item)
+				} else {
+					head.Remove(item)
+				}
+			}
+		}()
+	}
+
+	for round := 0; round < 20; round++ {
+		snap := head.Snapshot()
+		first := snap.ToSortedArray()
+		time.Sleep(time.Millisecond)
+		second := snap.ToSortedArray()
+		if len(first) != len(second) {
+			t.Errorf("Snapshot changed from %d to %d items", len(first), len(second))
+		} else {
+			for index := range first {
+				if first[index] != second[index] {
+					t.Errorf("Snapshot item %d changed", index)
+					break
+				}
+			}
+		}
+		snap.Release()
+	}
+
+	close(stop)
+	wg.Wait()
+
+	head.Snapshot().Release()
+	if err := head.Validate(); err != nil {
+		t.Errorf("Invalid after concurrent snapshots: %v", err)
+	}
+	linked = 0
+	for curr := head.head.loadNext(0); curr != nil; curr = curr.loadNext(0) {
+		linked++
+	}
+	if linked != head.Len() {
+		t.Errorf("%d items linked but Len is %d", linked, head.Len())
+	}
+
+	fmt.Println("OK!")
+	fmt.Println("----------------------------------------")
+}
+
+func TestBatch(t *testing.T) {
+	fmt.Println("---------------------------------------")
+	fmt.Println("Atomic batches")
+	fmt.Println("----------------------------------------")
+
+	var head = New(0.5, 30, FAST)
+	for i := 0; i < 10; i++ {
+		head.Insert(Int(i))
+	}
+	before := head.Snapshot()
+
+	// move an item
+	var move Batch
+	move.Remove(Int(3))
+	move.Insert(Int(20))
+	if err := head.Apply(&move); err != nil {
+		t.Errorf("Apply failed: %v", err)
+	}
+	if head.Contains(Int(3)) || !head.Contains(Int(20)) || head.Len() != 10 {
+		t.Errorf("Batch not applied")
+	}
+	if !before.Contains(Int(3)) || before.Contains(Int(20)) {
+		t.Errorf("Snapshot sees the batch")
+	}
+	before.Release()
+
+	// nothing is applied on failure
+	failing := []struct {
+		inserts, removes []int
+	}{
+		{[]int{30, 31}, []int{100}},
+		{[]int{30, 5}, nil},
+		{[]int{30}, []int{3}},
+		{[]int{30, 30}, nil},
+		{[]int{30}, []int{30}},
+	}
+	for _, test := range failing {
+		var batch Batch
+		for _, item := range test.inserts {
+			batch.Insert(Int(item))
+		}
+		for _, item := range test.removes {
+			batch.Remove(Int(item))
+		}
+		if err := head.Apply(&batch); err == nil {
+			t.Errorf("Batch %v should fail", test)
+		}
+		if head.Contains(Int(30)) || head.Contains(Int(31)) || head.Len() != 10 {
+			t.Errorf("Failed batch %v left changes behind", test)
+		}
+		if err := head.Validate(); err != nil {
+			t.Errorf("Invalid after rollback: %v", err)
+		}
+	}
+
+	// every key k is either at k or at k+1000, batches
+	// move random keys back and forth concurrently
+	head = New(0.5, 30, FAST)
+	const keys = 100
+	for k := 0; k < keys; k++ {
+		head.Insert(Int(k))
+	}
+
+	const movers = 4
+	var wg sync.WaitGroup
+	wg.Add(movers)
+	for index := 0; index < movers; index++ {
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 200; i++ {
+				var batch Batch
+				for _, k := range rand.Perm(keys)[:3] {
+					if head.Contains(Int(k)) {
+						batch.Remove(Int(k))
+						batch.Insert(Int(k + 1000))
+					} else {
+						batch.Remove(Int(k + 1000))
+						batch.Insert(Int(k))
+					}
+				}
+				// may fail when racing an other mover
+				head.Apply(&batch)
+			}
+		}()
+	}
+
+	for round := 0; round < 20; round++ {
+		snap := head.Snapshot()
+		for k := 0; k < keys; k++ {
+			if snap.Contains(Int(k)) == snap.Contains(Int(k+1000)) {
+				t.Errorf("Snapshot sees part of a batch for key %d", k)
+				break
+			}
+		}
+		snap.Release()
+		time.Sleep(time.Millisecond)
+	}
+	wg.Wait()
+
+	if err := head.Validate(); err != nil {
+		t.Errorf("Invalid after concurrent batches: %v", err)
+	}
+	if head.Len() != keys || len(head.ToSortedArray()) != keys {
+		t.Errorf("Len is %d after concurrent batches, should be %d", head.Len(), keys)
+	}
+
+	fmt.Println("OK!")
+	fmt.Println("----------------------------------------")
+}
+
+// counter item ordered by key only
+type counter struct {
+	key, count int
+}
+
+func (a counter) Less(b SkiplistItem) bool {
+	c, ok := b.(counter)
+	return ok && a.key < c.key
+}
+
+func (a counter) Equals(b SkiplistItem) bool {
+	c, ok := b.(counter)
+	return ok && a.key == c.key
+}
+
+func TestCompute(t *testing.T) {
+	fmt.Println("---------------------------------------")
+	fmt.Println("Compute")
+	fmt.Println("----------------------------------------")
+
+	var head = New(0.5, 30, FAST)
+
+	increment := func(key int) {
+		head.Compute(counter{key: key}, func(old SkiplistItem, exists bool) (SkiplistItem, ComputeOp) {
+			if !exists {
+				return counter{key, 1}, ComputeSet
+			}
+			return counter{key, old.(counter).count + 1}, ComputeSet
+		})
+	}
+
+	// no lost updates
+	const keys, routines, rounds = 10, 8, 500
+	var wg sync.WaitGroup
+	wg.Add(routines)
+	for index := 0; index < routines; index++ {
+		go func() {
+			defer wg.Done()
+			for i := 0; i < rounds; i++ {
+				increment(i % keys)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if head.Len() != keys {
+		t.Errorf("Len is %d, should be %d", head.Len(), keys)
+	}
+	for key := 0; key < keys; key++ {
+		if count := head.Get(counter{key: key}).(counter).count; count != routines*rounds/keys {
+			t.Errorf("Count of %d is %d, should be %d", key, count, routines*rounds/keys)
+		}
+	}
+
+	// replacing keeps older versions for snapshots
+	snap := head.Snapshot()
+	events := head.Subscribe(nil, nil, 1, OverflowDrop)
+	increment(0)
+	if count := snap.Get(counter{key: 0}).(counter).count; count != routines*rounds/keys {
+		t.Errorf("Snapshot sees the update, count %d", count)
+	}
+	if event := <-events.Events(); event.Op != EventUpdate || event.Item.(counter).count != routines*rounds/keys+1 {
+		t.Errorf("Wrong event %v", event)
+	}
+	events.Close()
+	snap.Release()
+
+	// keep and delete
+	item, exists := head.Compute(counter{key: 100}, func(old SkiplistItem, exists bool) (SkiplistItem, ComputeOp) {
+		return nil, ComputeKeep
+	})
+	if item != nil || exists || head.Contains(counter{key: 100}) {
+		t.Errorf("Keep inserted an item")
+	}
+	item, exists = head.Compute(counter{key: 1}, func(old SkiplistItem, exists bool) (SkiplistItem, ComputeOp) {
+		return nil, ComputeDelete
+	})
+	if item != nil || exists || head.Contains(counter{key: 1}) || head.Len() != keys-1 {
+		t.Errorf("Delete left the item")
+	}
+
+	// items not equal to the key are refused
+	for _, key := range []int{2, 200} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("Setting an other key should panic")
+				}
+			}()
+			head.Compute(counter{key: key}, func(old SkiplistItem, exists bool) (SkiplistItem, ComputeOp) {
+				return counter{key + 1, 0}, ComputeSet
+			})
+		}()
+		increment(key)
+	}
+
+	// a panicking callback leaves nothing locked or claimed
+	for _, key := range []int{3, 300} {
+		item := counter{key: key}
+		present := head.Contains(item)
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("Panic of the callback was not passed on")
+				}
+			}()
+			head.Compute(item, func(old SkiplistItem, exists bool) (SkiplistItem, ComputeOp) {
+				panic("callback failed")
+			})
+		}()
+		if head.Contains(item) != present {
+			t.Errorf("Panicking callback changed %d", key)
+		}
+
+		done := make(chan bool)
+		go func() {
+			if present {
+				done <- head.Remove(item) && head.Insert(item)
+			} else {
+				done <- head.Insert(item) && head.Remove(item)
+			}
+		}()
+		select {
+		case ok := <-done:
+			if !ok {
+				t.Errorf("Writing %d after a panicking callback failed", key)
+			}
+		case <-time.After(10 * time.Second):
+			t.Fatalf("Writing %d after a panicking callback hangs", key)
+		}
+	}
+
+	if err := head.Validate(); err != nil {
+		t.Errorf("Invalid after compute: %v", err)
+	}
+	linked := 0
+	for curr := head.head.loadNext(0); curr != nil; curr = curr.loadNext(0) {
+		linked++
+	}
+	if linked != head.Len() || head.Len() != keys {
+		t.Errorf("%d items linked but Len is %d", linked, head.Len())
+	}
+
+	fmt.Println("OK!")
+	fmt.Println("----------------------------------------")
+}
+
+func TestTryInsertRemove(t *testing.T) {
+	fmt.Println("---------------------------------------")
+	fmt.Println("Try insert and remove")
+	fmt.Println("----------------------------------------")
+
+	var head = New(0.5, 30, FAST)
+
+	if err := head.TryInsert(Int(1)); err != nil {
+		t.Fatalf("insert failed: %v", err)
+	}
+	if err := head.TryInsert(Int(1)); !errors.Is(err, ErrExists) {
+		t.Fatalf("expected ErrExists, got %v", err)
+	}
+	if err := head.TryRemove(Int(2)); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+	if err := head.TryRemove(Int(1)); err != nil {
+		t.Fatalf("remove failed: %v", err)
+	}
+	if err := head.TryRemove(Int(1)); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+
+	// failed batches report the same sentinels
+	var batch Batch
+	batch.Remove(Int(1))
+	if err := head.Apply(&batch); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected ErrNotFound from Apply, got %v", err)
+	}
+	head.Insert(Int(1))
+	batch = Batch{}
+	batch.Insert(Int(1))
+	if err := head.Apply(&batch); !errors.Is(err, ErrExists) {
+		t.Fatalf("expected ErrExists from Apply, got %v", err)
+	}
+	head.Remove(Int(1))
+
+	// nothing happens once cancelled
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := head.TryInsertContext(ctx, Int(3)); !errors.Is(err, ErrCancelled) {
+		t.Fatalf("expected ErrCancelled, got %v", err)
+	}
+	if head.Contains(Int(3)) {
+		t.Fatalf("cancelled insert took effect")
+	}
+	head.Insert(Int(3))
+	if err := head.TryRemoveContext(ctx, Int(3)); !errors.Is(err, ErrCancelled) {
+		t.Fatalf("expected ErrCancelled, got %v", err)
+	}
+	if !head.Contains(Int(3)) {
+		t.Fatalf("cancelled remove took effect")
+	}
+
+	// exactly one of the routines removing an item wins
+	const items, routines = 200, 8
+	for index := 0; index < items; index++ {
+		head.Insert(Int(index + 10))
+	}
+	var wg sync.WaitGroup
+	var mux sync.Mutex
+	wins := make(map[int]int)
+	wg.Add(routines)
+	for r := 0; r < routines; r++ {
+		go func() {
+			defer wg.Done()
+			for index := 0; index < items; index++ {
+				err := head.TryRemove(Int(index + 10))
+				switch {
+				case err == nil:
+					mux.Lock()
+					wins[index]++
+					mux.Unlock()
+				case !errors.Is(err, ErrConcurrentRemoval) && !errors.Is(err, ErrNotFound):
+					t.Errorf("unexpected error %v", err)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	for index := 0; index < items; index++ {
+		if wins[index] != 1 {
+			t.Fatalf("item %d removed %d times", index+10, wins[index])
+		}
+	}
+	if head.Len() != 1 {
+		t.Fatalf("expected 1 item left, got %d", head.Len())
+	}
+
+	fmt.Println("OK!")
+	fmt.Println("----------------------------------------")
+}
+
+// intervalIn true if iv is one of intervals
+func intervalIn(intervals []Interval, iv Interval) bool {
+	for _, other := range intervals {
+		if other == iv {
+			return true
+		}
+	}
+	return false
+}
+
+func TestIntervalSkiplist(t *testing.T) {
+	fmt.Println("---------------------------------------")
+	fmt.Println("Interval skiplist")
+	fmt.Println("----------------------------------------")
+
+	rand.Seed(time.Now().UTC().UnixNano())
+
+	var head = NewIntervalSkiplist(0.5, 30, FAST)
+
+	if head.Insert(Interval{Int(3), Int(3)}) || head.Insert(Interval{Int(4), Int(2)}) {
+		t.Fatalf("inserted an empty interval")
+	}
+
+	// checked against every stored interval
+	stored := make(map[Interval]bool)
+	check := func() {
+		for point := -1; point <= 101; point++ {
+			var expected []Interval
+			for iv := range stored {
+				if iv.Contains(Int(point)) {
+					expected = append(expected, iv)
+				}
+			}
+			sortIntervals(expected)
+			if got := head.Stab(Int(point)); fmt.Sprint(got) != fmt.Sprint(expected) {
+				t.Fatalf("stab %d: expected %v, got %v", point, expected, got)
+			}
+		}
+
+		lo := rand.Intn(100)
+		hi := lo + 1 + rand.Intn(20)
+		var expected []Interval
+		for iv := range stored {
+			if iv.Overlaps(Int(lo), Int(hi)) {
+				expected = append(expected, iv)
+			}
+		}
+		sortIntervals(expected)
+		if got := head.Overlap(Int(lo), Int(hi)); fmt.Sprint(got) != fmt.Sprint(expected) {
+			t.Fatalf("overlap [%d, %d): expected %v, got %v", lo, hi, expected, got)
+		}
+
+		if head.Len() != len(stored) {
+			t.Fatalf("expected %d intervals, got %d", len(stored), head.Len())
+		}
+	}
+
+	for round := 0; round < 2000; round++ {
+		lo := rand.Intn(100)
+		iv := Interval{Int(lo), Int(lo + 1 + rand.Intn(30))}
+
+		if rand.Intn(3) == 0 {
+			if head.Remove(iv) != stored[iv] {
+				t.Fatalf("remove of %v disagrees", iv)
+			}
+			delete(stored, iv)
+		} else {
+			if head.Insert(iv) == stored[iv] {
+				t.Fatalf("insert of %v disagrees", iv)
+			}
+			stored[iv] = true
+		}
+
+		if round%20 == 0 {
+			check()
+		}
+	}
+
+	// emptied, nothing left behind
+	for iv := range stored {
+		head.Remove(iv)
+		delete(stored, iv)
+	}
+	check()
+	if head.points.nLevels != 1 || head.points.head.loadNext(0) != nil {
+		t.Fatalf("endpoints left after removing every interval")
+	}
+
+	// writers and readers in parallel
+	var wg sync.WaitGroup
+	wg.Add(8)
+	for r := 0; r < 8; r++ {
+		go func(r int) {
+			defer wg.Done()
+			for index := 0; index < 500; index++ {
+				iv := Interval{Int(index), Int(index + 10 + r)}
+				if r%2 == 0 {
+					head.Insert(iv)
+					head.Remove(iv)
+				} else {
+					for _, found := range head.Stab(Int(index)) {
+						if !found.Contains(Int(index)) {
+							t.Errorf("stab %d returned %v", index, found)
+						}
+					}
+				}
+			}
+		}(r)
+	}
+	wg.Wait()
+
+	if head.Len() != 0 {
+		t.Fatalf("expected no interval left, got %d", head.Len())
+	}
+
+	// intervals kept throughout are always found, while others
+	// come and go around them
+	kept := []Interval{{Int(0), Int(100)}, {Int(20), Int(40)}, {Int(30), Int(70)}}
+	for _, iv := range kept {
+		head.Insert(iv)
+		stored[iv] = true
+	}
+	wg.Add(8)
+	for r := 0; r < 8; r++ {
+		go func(r int) {
+			defer wg.Done()
+			random := rand.New(rand.NewSource(int64(r)))
+			for index := 0; index < 500; index++ {
+				lo := random.Intn(100)
+				iv := Interval{Int(lo), Int(lo + 1 + random.Intn(30))}
+				if stored[iv] {
+					continue
+				}
+				if r%2 == 0 {
+					head.Insert(iv)
+					head.Remove(iv)
+					continue
+				}
+				found := head.Overlap(Int(lo), Int(lo+1))
+				for _, want := range kept {
+					if want.Contains(Int(lo)) && !intervalIn(found, want) {
+						t.Errorf("stab %d missed %v", lo, want)
+					}
+				}
+				for _, iv := range found {
+					if !iv.Contains(Int(lo)) {
+						t.Errorf("stab %d returned %v", lo, iv)
+					}
+				}
+			}
+		}(r)
+	}
+	wg.Wait()
+	check()
+
+	fmt.Println("OK!")
+	fmt.Println("----------------------------------------")
+}
+
+// sumMonoid sum of Int items
+type sumMonoid struct{}
+
+func (sumMonoid) Identity() interface{}                 { return 0 }
+func (sumMonoid) Measure(item SkiplistItem) interface{} { return int(item.(Int)) }
+func (sumMonoid) Combine(a, b interface{}) interface{}  { return a.(int) + b.(int) }
+
+// orderMonoid items in the order combined, to catch swapped operands
+type orderMonoid struct{}
+
+func (orderMonoid) Identity() interface{}                 { return "" }
+func (orderMonoid) Measure(item SkiplistItem) interface{} { return fmt.Sprintf("%d,", item) }
+func (orderMonoid) Combine(a, b interface{}) interface{}  { return a.(string) + b.(string) }
+
+func TestAggregate(t *testing.T) {
+	fmt.Println("---------------------------------------")
+	fmt.Println("Range aggregates")
+	fmt.Println("----------------------------------------")
+
+	rand.Seed(time.Now().UTC().UnixNano())
+
+	var sums = NewAggregateSkiplist(0.5, 30, FAST, sumMonoid{})
+	var orders = NewAggregateSkiplist(0.5, 30, VARIABLE, orderMonoid{})
+
+	if sums.Aggregate(nil, nil) != 0 {
+		t.Fatalf("expected identity of an empty list")
+	}
+
+	stored := make(map[int]bool)
+	check := func(lo, hi SkiplistItem) {
+		sum, order := 0, ""
+		for key := 0; key < 1000; key++ {
+			if stored[key] && (lo == nil || !Int(key).Less(lo)) && (hi == nil || Int(key).Less(hi)) {
+				sum += key
+				order += fmt.Sprintf("%d,", key)
+			}
+		}
+		if got := sums.Aggregate(lo, hi); got != sum {
+			t.Fatalf("sum of [%v, %v): expected %d, got %v", lo, hi, sum, got)
+		}
+		if got := orders.Aggregate(lo, hi); got != order {
+			t.Fatalf("order of [%v, %v): expected %s, got %v", lo, hi, order, got)
+		}
+	}
+
+	for round := 0; round < 5000; round++ {
+		key := rand.Intn(1000)
+		if rand.Intn(3) == 0 {
+			if sums.Remove(Int(key)) != stored[key] || orders.Remove(Int(key)) != stored[key] {
+				t.Fatalf("remove of %d disagrees", key)
+			}
+			delete(stored, key)
+		} else {
+			if sums.Insert(Int(key)) == stored[key] || orders.Insert(Int(key)) == stored[key] {
+				t.Fatalf("insert of %d disagrees", key)
+			}
+			stored[key] = true
+		}
+
+		if round%50 == 0 {
+			lo := rand.Intn(1000)
+			check(Int(lo), Int(lo+rand.Intn(300)))
+			check(nil, Int(lo))
+			check(Int(lo), nil)
+			check(nil, nil)
+		}
+	}
+
+	if sums.Len() != len(stored) {
+		t.Fatalf("expected %d items, got %d", len(stored), sums.Len())
+	}
+
+	// writers and readers in parallel
+	var wg sync.WaitGroup
+	wg.Add(8)
+	for r := 0; r < 8; r++ {
+		go func(r int) {
+			defer wg.Done()
+			for index := 0; index < 500; index++ {
+				key := Int(1000 + r*1000 + index)
+				if r%2 == 0 {
+					sums.Insert(key)
+					sums.Remove(key)
+				} else {
+					sums.Aggregate(Int(index), Int(index+100))
+				}
+			}
+		}(r)
+	}
+	wg.Wait()
+	check(nil, nil)
+
+	fmt.Println("OK!")
+	fmt.Println("----------------------------------------")
+}
+
+func TestSplitJoin(t *testing.T) {
+	fmt.Println("---------------------------------------")
+	fmt.Println("Split and join")
+	fmt.Println("----------------------------------------")
+
+	rand.Seed(time.Now().UTC().UnixNano())
+
+	var head = New(0.5, 30, FAST)
+	for index := 0; index < dataAmount; index++ {
+		head.Insert(Int(rand.Intn(4 * dataAmount)))
+	}
+	all := head.ToSortedArray()
+
+	for round := 0; round < 20; round++ {
+		pivot := Int(rand.Intn(5*dataAmount) - dataAmount/2)
+
+		left, right := head.Split(pivot)
+		if left != head {
+			t.Fatalf("left half is not the split list")
+		}
+		for _, half := range []*Skiplist{left, right} {
+			if err := half.Validate(); err != nil {
+				t.Fatalf("invalid half after split at %v: %v", pivot, err)
+			}
+		}
+
+		var below, above []SkiplistItem
+		for _, item := range all {
+			if item.Less(pivot) {
+				below = append(below, item)
+			} else {
+				above = append(above, item)
+			}
+		}
+		if fmt.Sprint(left.ToSortedArray()) != fmt.Sprint(below) ||
+			fmt.Sprint(right.ToSortedArray()) != fmt.Sprint(above) {
+			t.Fatalf("wrong halves after split at %v", pivot)
+		}
+		if left.Len() != len(below) || right.Len() != len(above) {
+			t.Fatalf("expected lengths %d and %d, got %d and %d",
+				len(below), len(above), left.Len(), right.Len())
+		}
+
+		// overlapping ranges are refused
+		if len(below) > 0 && len(above) > 0 {
+			if _, err := Join(right, left); err == nil {
+				t.Fatalf("joined overlapping lists")
+			}
+		}
+
+		joined, err := Join(left, right)
+		if err != nil || joined != left {
+			t.Fatalf("join failed: %v", err)
+		}
+		if err := joined.Validate(); err != nil {
+			t.Fatalf("invalid list after join: %v", err)
+		}
+		if right.Len() != 0 || right.Min() != nil {
+			t.Fatalf("joined list not emptied")
+		}
+		if fmt.Sprint(joined.ToSortedArray()) != fmt.Sprint(all) || joined.Len() != len(all) {
+			t.Fatalf("wrong items after join")
+		}
+
+		// both stay usable
+		right.Insert(Int(-1))
+		if !joined.Insert(Int(5*dataAmount)) || !joined.Remove(Int(5*dataAmount)) {
+			t.Fatalf("joined list not usable")
+		}
+	}
+
+	// halves changed by concurrent writers, then joined
+	left, right := head.Split(Int(2 * dataAmount))
+	below, above := 0, 0
+	for _, item := range all {
+		if item.Less(Int(2 * dataAmount)) {
+			below++
+		} else {
+			above++
+		}
+	}
+	var wg sync.WaitGroup
+	wg.Add(nRoutinesToUse)
+	for index := 0; index < nRoutinesToUse; index++ {
+		go func(v int) {
+			defer wg.Done()
+			half, offset := left, -dataAmount
+			if v%2 == 1 {
+				half, offset = right, 5*dataAmount
+			}
+			for i := 0; i < 10; i++ {
+				half.Insert(Int(offset + v*10 + i))
+			}
+		}(index)
+	}
+	wg.Wait()
+	inserted := 10 * nRoutinesToUse / 2
+	if left.Len() != below+inserted || right.Len() != above+inserted {
+		t.Fatalf("expected lengths %d and %d, got %d and %d",
+			below+inserted, above+inserted, left.Len(), right.Len())
+	}
+
+	Join(left, right)
+
+	left, right = head.Split(Int(2 * dataAmount))
+	if joined, _ := Join(left, right); joined.Len() != len(all)+2*inserted {
+		t.Fatalf("expected %d items after join, got %d", len(all)+2*inserted, joined.Len())
+	}
+
+	fmt.Println("OK!")
+	fmt.Println("----------------------------------------")
+}
+
+func TestFinger(t *testing.T) {
+	fmt.Println("---------------------------------------")
+	fmt.Println("Finger search")
+	fmt.Println("----------------------------------------")
+
+	rand.Seed(time.Now().UTC().UnixNano())
+
+	var head = New(0.5, 30, FAST)
+	for index := 0; index < dataAmount; index += 2 {
+		head.Insert(Int(index))
+	}
+
+	finger := head.Finger()
+
+	// forward, backward and random lookups agree with Contains
+	check := func(keys []int) {
+		for _, key := range keys {
+			if finger.Contains(Int(key)) != head.Contains(Int(key)) {
+				t.Fatalf("finger disagrees on %d", key)
+			}
+			if item := finger.Get(Int(key)); item != nil && item != Int(key) {
+				t.Fatalf("finger got %v for %d", item, key)
+			}
+		}
+	}
+	var forward, backward, random []int
+	for index := -1; index <= dataAmount; index++ {
+		forward = append(forward, index)
+		backward = append(backward, dataAmount-index)
+		random = append(random, rand.Intn(dataAmount+2)-1)
+	}
+	check(forward)
+	check(backward)
+	check(random)
+
+	// inserts and removes through the finger
+	for index := 1; index < dataAmount; index += 2 {
+		if !finger.Insert(Int(index)) {
+			t.Fatalf("finger insert of %d failed", index)
+		}
+	}
+	for index := dataAmount - 1; index >= 0; index -= 3 {
+		if !finger.Remove(Int(index)) {
+			t.Fatalf("finger remove of %d failed", index)
+		}
+	}
+	if err := head.Validate(); err != nil {
+		t.Fatalf("invalid after finger updates: %v", err)
+	}
+	check(random)
+
+	// nodes removed underneath a scanning finger
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for index := 0; index < dataAmount; index++ {
+			head.Remove(Int(rand.Intn(dataAmount)))
+		}
+	}()
+	for round := 0; round < 20; round++ {
+		for index := 0; index < dataAmount; index += 7 {
+			if item := finger.Get(Int(index)); item != nil && item != Int(index) {
+				t.Errorf("finger got %v for %d", item, index)
+			}
+		}
+	}
+	wg.Wait()
+	check(forward)
+	check(backward)
+
+	// contents replaced, the old position is dropped
+	head.FromSorted([]SkiplistItem{Int(5), Int(6)})
+	check([]int{4, 5, 6, 7, 5})
+
+	// positioned in the part moved away by Split, then back by Join
+	head = New(0.5, 30, FAST)
+	for index := 0; index < 100; index++ {
+		head.Insert(Int(index))
+	}
+	finger = head.Finger()
+	if !finger.Contains(Int(70)) {
+		t.Fatalf("finger misses 70")
+	}
+	left, right := head.Split(Int(50))
+	if finger.Contains(Int(70)) || !finger.Insert(Int(75)) || !finger.Contains(Int(75)) {
+		t.Fatalf("finger searches the other half after split")
+	}
+	if left.Len() != 51 || right.Len() != 50 || left.Validate() != nil || right.Validate() != nil {
+		t.Fatalf("finger insert after split broke the halves")
+	}
+	if !left.Remove(Int(75)) {
+		t.Fatalf("item inserted through the finger not in its list")
+	}
+	Join(left, right)
+	if !finger.Contains(Int(70)) || !finger.Remove(Int(70)) || finger.Contains(Int(70)) {
+		t.Fatalf("finger misses items joined back")
+	}
+	if head.Len() != 99 || head.Validate() != nil {
+		t.Fatalf("finger remove after join broke the list")
+	}
+
+	fmt.Println("OK!")
+	fmt.Println("----------------------------------------")
+}
+
+func TestClone(t *testing.T) {
+	fmt.Println("---------------------------------------")
+	fmt.Println("Clone")
+	fmt.Println("----------------------------------------")
+
+	rand.Seed(time.Now().UTC().UnixNano())
+
+	var head = New(0.3, 20, VARIABLE)
+	head.SetCapacity(5*dataAmount, 0)
+	for index := 0; index < dataAmount; index++ {
+		head.Insert(Int(rand.Intn(4 * dataAmount)))
+	}
+
+	clone := head.Clone()
+	if err := clone.Validate(); err != nil {
+		t.Fatalf("invalid clone: %v", err)
+	}
+	if clone.prob != head.prob || clone.maxLevels != head.maxLevels || clone.fastRandom != head.fastRandom ||
+		clone.maxElements != head.maxElements || clone.Height() != head.Height() || clone.Len() != head.Len() {
+		t.Fatalf("clone parameters differ")
+	}
+
+	// same structure, distinct nodes
+	for a, b := head.head.loadNext(0), clone.head.loadNext(0); a != nil || b != nil; a, b = a.loadNext(0), b.loadNext(0) {
+		if a == nil || b == nil || a == b || !a.value.Equals(b.value) || a.topLevel != b.topLevel {
+			t.Fatalf("clone structure differs")
+		}
+	}
+
+	// independent of the source
+	clone.Insert(Int(-1))
+	head.Remove(head.Min())
+	if head.Contains(Int(-1)) || clone.Len() != head.Len()+2 {
+		t.Fatalf("clone shares items with the source")
+	}
+
+	// every key k is either at k or at k+1000 while
+	// batches move random keys back and forth
+	head = New(0.5, 30, FAST)
+	const keys = 100
+	for k := 0; k < keys; k++ {
+		head.Insert(Int(k))
+	}
+
+	const movers = 4
+	var wg sync.WaitGroup
+	wg.Add(movers)
+	for index := 0; index < movers; index++ {
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 200; i++ {
+				var batch Batch
+				for _, k := range rand.Perm(keys)[:3] {
+					if head.Contains(Int(k)) {
+						batch.Remove(Int(k))
+						batch.Insert(Int(k + 1000))
+					} else {
+						batch.Remove(Int(k + 1000))
+						batch.Insert(Int(k))
+					}
+				}
+				// may fail when racing an other mover
+				head.Apply(&batch)
+			}
+		}()
+	}
+
+	for round := 0; round < 20; round++ {
+		clone := head.Clone()
+		for k := 0; k < keys; k++ {
+			if clone.Contains(Int(k)) == clone.Contains(Int(k+1000)) {
+				t.Errorf("Clone holds part of a batch for key %d", k)
+				break
+			}
+		}
+		if err := clone.Validate(); err != nil || clone.Len() != keys {
+			t.Errorf("Clone of %d items invalid: %v", clone.Len(), err)
+		}
+		time.Sleep(time.Millisecond)
+	}
+	wg.Wait()
+
+	fmt.Println("OK!")
+	fmt.Println("----------------------------------------")
+}
+
+func TestSetPredicates(t *testing.T) {
+	fmt.Println("---------------------------------------")
+	fmt.Println("Set predicates")
+	fmt.Println("----------------------------------------")
+
+	rand.Seed(time.Now().UTC().UnixNano())
+
+	fill := func(keys ...int) *Skiplist {
+		list := New(0.5, 30, FAST)
+		for _, key := range keys {
+			list.Insert(Int(key))
+		}
+		return list
+	}
+
+	empty := fill()
+	evens := fill(0, 2, 4, 6, 8)
+	odds := fill(1, 3, 5, 7, 9)
+	some := fill(2, 6)
+
+	cases := []struct {
+		a, b                                 *Skiplist
+		equal, subset, disjoint, overlapping bool
+	}{
+		{empty, empty, true, true, true, false},
+		{empty, evens, false, true, true, false},
+		{evens, empty, false, false, true, false},
+		{evens, fill(8, 6, 4, 2, 0), true, true, false, true},
+		{some, evens, false, true, false, true},
+		{evens, some, false, false, false, true},
+		{evens, odds, false, false, true, false},
+		{some, fill(2, 5, 7), false, false, false, true},
+	}
+	for index, c := range cases {
+		if c.a.Equal(c.b) != c.equal || c.a.SubsetOf(c.b) != c.subset ||
+			c.a.Disjoint(c.b) != c.disjoint || c.a.Overlaps(c.b) != c.overlapping {
+			t.Fatalf("case %d: wrong set relation", index)
+		}
+	}
+
+	// removed items are not compared
+	other := fill(0, 2, 4, 6, 8, 10)
+	other.Remove(Int(10))
+	if !evens.Equal(other) {
+		t.Fatalf("removed item compared")
+	}
+
+	// against sets built by hand
+	for round := 0; round < 200; round++ {
+		inA, inB := make(map[int]bool), make(map[int]bool)
+		a, b := New(0.5, 30, FAST), New(0.5, 30, FAST)
+		for index := 0; index < rand.Intn(50); index++ {
+			key := rand.Intn(100)
+			inA[key] = true
+			a.Insert(Int(key))
+		}
+		for index := 0; index < rand.Intn(200); index++ {
+			key := rand.Intn(100)
+			inB[key] = true
+			b.Insert(Int(key))
+		}
+
+		subset, common := true, false
+		for key := range inA {
+			subset = subset && inB[key]
+			common = common || inB[key]
+		}
+		equal := subset && len(inA) == len(inB)
+
+		if a.Equal(b) != equal || a.SubsetOf(b) != subset || a.Disjoint(b) == common || a.Overlaps(b) != common {
+			t.Fatalf("wrong set relation for %v and %v", a.ToSortedArray(), b.ToSortedArray())
+		}
+	}
+
+	fmt.Println("OK!")
+	fmt.Println("----------------------------------------")
+}
+
+func TestSetIterators(t *testing.T) {
+	fmt.Println("---------------------------------------")
+	fmt.Println("Lazy set iterators")
+	fmt.Println("----------------------------------------")
+
+	rand.Seed(time.Now().UTC().UnixNano())
+
+	// drain collects what remains of it
+	drain := func(it Iterator) []SkiplistItem {
+		var items []SkiplistItem
+		for it.Next() {
+			items = append(items, it.Item())
+		}
+		return items
+	}
+	// expected items of 0..199 accepted by keep, from lo on
+	expect := func(lo int, keep func(key int) bool) []SkiplistItem {
+		var items []SkiplistItem
+		for key := lo; key < 200; key++ {
+			if keep(key) {
+				items = append(items, Int(key))
+			}
+		}
+		return items
+	}
+
+	// seek on list iterators, both directions
+	var head = New(0.5, 30, FAST)
+	for key := 0; key < 100; key += 10 {
+		head.Insert(Int(key))
+	}
+	head.Remove(Int(50))
+	it := head.Iterator()
+	if !it.Seek(Int(45)) || it.Item() != Int(60) || !it.Next() || it.Item() != Int(70) {
+		t.Fatalf("forward seek went wrong")
+	}
+	if it.Seek(Int(91)) || it.Next() {
+		t.Fatalf("forward seek past the end found an item")
+	}
+	reverse := head.ReverseIterator()
+	if !reverse.Seek(Int(55)) || reverse.Item() != Int(40) || !reverse.Next() || reverse.Item() != Int(30) {
+		t.Fatalf("reverse seek went wrong")
+	}
+	if !reverse.Seek(Int(90)) || reverse.Item() != Int(90) || reverse.Seek(Int(-1)) {
+		t.Fatalf("reverse seek to the ends went wrong")
+	}
+
+	for round := 0; round < 50; round++ {
+		sets := make([]map[int]bool, 3)
+		lists := make([]*Skiplist, 3)
+		for index := range sets {
+			sets[index] = make(map[int]bool)
+			lists[index] = New(0.5, 30, FAST)
+			for n := rand.Intn(150); n > 0; n-- {
+				key := rand.Intn(200)
+				sets[index][key] = true
+				lists[index].Insert(Int(key))
+			}
+		}
+		a, b, c := sets[0], sets[1], sets[2]
+		iters := func() []SeekIterator {
+			return []SeekIterator{lists[0].Iterator(), lists[1].Iterator(), lists[2].Iterator()}
+		}
+
+		cases := []struct {
+			name string
+			it   func() SeekIterator
+			keep func(key int) bool
+		}{
+			{"union", func() SeekIterator { return UnionIterator(iters()...) },
+				func(k int) bool { return a[k] || b[k] || c[k] }},
+			{"intersection", func() SeekIterator { return IntersectionIterator(iters()[:2]...) },
+				func(k int) bool { return a[k] && b[k] }},
+			{"difference", func() SeekIterator { return DifferenceIterator(iters()[0], iters()[1:]...) },
+				func(k int) bool { return a[k] && !b[k] && !c[k] }},
+			{"symmetric difference", func() SeekIterator { return SymmetricDifferenceIterator(iters()...) },
+				func(k int) bool {
+					n := 0
+					for _, s := range sets {
+						if s[k] {
+							n++
+						}
+					}
+					return n%2 == 1
+				}},
+			{"(A ∩ B) − C", func() SeekIterator {
+				its := iters()
+				return DifferenceIterator(IntersectionIterator(its[0], its[1]), its[2])
+			}, func(k int) bool { return a[k] && b[k] && !c[k] }},
+			{"(A ∪ B) ∩ C", func() SeekIterator {
+				its := iters()
+				return IntersectionIterator(UnionIterator(its[0], its[1]), its[2])
+			}, func(k int) bool { return (a[k] || b[k]) && c[k] }},
+		}
+
+		for _, cs := range cases {
+			if got, want := drain(cs.it()), expect(0, cs.keep); fmt.Sprint(got) != fmt.Sprint(want) {
+				t.Fatalf("%s: expected %v, got %v", cs.name, want, got)
+			}
+
+			// seek, then go on
+			lo := rand.Intn(200)
+			it := cs.it()
+			want := expect(lo, cs.keep)
+			if it.Seek(Int(lo)) != (len(want) > 0) {
+				t.Fatalf("%s: seek to %d disagrees", cs.name, lo)
+			}
+			if len(want) > 0 {
+				got := append([]SkiplistItem{it.Item()}, drain(it)...)
+				if fmt.Sprint(got) != fmt.Sprint(want) {
+					t.Fatalf("%s from %d: expected %v, got %v", cs.name, lo, want, got)
+				}
+			}
+		}
+	}
+
+	if UnionIterator().Next() || IntersectionIterator().Next() {
+		t.Fatalf("set iterator without inputs not empty")
+	}
+
+	fmt.Println("OK!")
+	fmt.Println("----------------------------------------")
+}
+
+func TestIntersectAll(t *testing.T) {
+	fmt.Println("---------------------------------------")
+	fmt.Println("Multi-way intersection")
+	fmt.Println("----------------------------------------")
+
+	rand.Seed(time.Now().UTC().UnixNano())
+
+	if IntersectAll().Len() != 0 || IntersectAllIterator().Next() {
+		t.Fatalf("intersection of no list not empty")
+	}
+
+	for round := 0; round < 20; round++ {
+		n := 5 + rand.Intn(16)
+		lists := make([]*Skiplist, n)
+		counts := make(map[int]int)
+		for index := range lists {
+			lists[index] = New(0.5, 30, FAST)
+			// dense lists keep the intersection from being empty
+			density := 2 + rand.Intn(10)
+			for key := 0; key < 2000; key++ {
+				if key%density != 0 && rand.Intn(20) != 0 {
+					lists[index].Insert(Int(key))
+					counts[key]++
+				}
+			}
+			lists[index].Remove(Int(7))
+		}
+		// removed from every list, it does not count
+		counts[7] = 0
+
+		var want []SkiplistItem
+		for key := 0; key < 2000; key++ {
+			if counts[key] == n {
+				want = append(want, Int(key))
+			}
+		}
+
+		intersected := IntersectAll(lists...)
+		if err := intersected.Validate(); err != nil {
+			t.Fatalf("invalid intersection: %v", err)
+		}
+		if got := intersected.ToSortedArray(); fmt.Sprint(got) != fmt.Sprint(want) {
+			t.Fatalf("intersection of %d lists: expected %v, got %v", n, want, got)
+		}
+
+		// seek then stream the rest
+		lo := rand.Intn(2000)
+		it := IntersectAllIterator(lists...)
+		var got []SkiplistItem
+		for ok := it.Seek(Int(lo)); ok; ok = it.Next() {
+			got = append(got, it.Item())
+		}
+		var rest []SkiplistItem
+		for _, item := range want {
+			if !item.Less(Int(lo)) {
+				rest = append(rest, item)
+			}
+		}
+		if fmt.Sprint(got) != fmt.Sprint(rest) || it.Next() {
+			t.Fatalf("intersection from %d: expected %v, got %v", lo, rest, got)
+		}
+
+		// composes with the set iterators
+		odd := New(0.5, 30, FAST)
+		for key := 1; key < 2000; key += 2 {
+			odd.Insert(Int(key))
+		}
+		diff := DifferenceIterator(IntersectAllIterator(lists...), odd.Iterator())
+		got = got[:0]
+		for diff.Next() {
+			got = append(got, diff.Item())
+		}
+		var even []SkiplistItem
+		for _, item := range want {
+			if item.(Int)%2 == 0 {
+				even = append(even, item)
+			}
+		}
+		if fmt.Sprint(got) != fmt.Sprint(even) {
+			t.Fatalf("intersection minus odd items: expected %v, got %v", even, got)
+		}
+	}
+
+	// a single list is its own intersection
+	single := New(0.5, 30, FAST)
+	single.Insert(Int(1))
+	single.Insert(Int(2))
+	if got := IntersectAll(single).ToSortedArray(); fmt.Sprint(got) != "[1 2]" {
+		t.Fatalf("intersection of a single list is %v", got)
+	}
+
+	fmt.Println("OK!")
+	fmt.Println("----------------------------------------")
+}
+
+func TestResolvers(t *testing.T) {
+	fmt.Println("---------------------------------------")
+	fmt.Println("Union and intersection with resolvers")
+	fmt.Println("----------------------------------------")
+
+	// counters 0..99 in a and 50..149 in b
+	skipa, skipb := New(0.5, 30, FAST), New(0.5, 30, FAST)
+	for key := 0; key < 100; key++ {
+		skipa.Insert(counter{key, 1})
+		skipb.Insert(counter{key + 50, 10})
+	}
+	skipa.Remove(counter{key: 60})
+
+	sum := func(a, b SkiplistItem) SkiplistItem {
+		return counter{a.(counter).key, a.(counter).count + b.(counter).count}
+	}
+	count := func(list *Skiplist, key int) int {
+		item := list.Get(counter{key: key})
+		if item == nil {
+			return -1
+		}
+		return item.(counter).count
+	}
+
+	merged, stats := New(0.5, 30, FAST).UnionWith(skipa, skipb, sum)
+	if err := merged.Validate(); err != nil {
+		t.Fatalf("invalid union: %v", err)
+	}
+	if stats != (MergeStats{FromA: 50, FromB: 51, Conflicts: 49}) || merged.Len() != 150 {
+		t.Fatalf("wrong union stats %+v for %d items", stats, merged.Len())
+	}
+	if count(merged, 0) != 1 || count(merged, 55) != 11 || count(merged, 60) != 10 || count(merged, 149) != 10 {
+		t.Fatalf("union not resolved")
+	}
+
+	// without resolver the item of skipb is kept, as by Union
+	merged, stats = New(0.5, 30, FAST).UnionWith(skipa, skipb, nil)
+	if stats.Conflicts != 49 || count(merged, 55) != 10 || count(New(0.5, 30, FAST).Union(skipa, skipb), 55) != 10 {
+		t.Fatalf("union without resolver changed")
+	}
+
+	intersected, stats := New(0.5, 30, FAST).IntersectionWith(skipa, skipb, sum)
+	if err := intersected.Validate(); err != nil {
+		t.Fatalf("invalid intersection: %v", err)
+	}
+	if stats != (MergeStats{Conflicts: 49}) || intersected.Len() != 49 || count(intersected, 99) != 11 || count(intersected, 60) != -1 {
+		t.Fatalf("wrong intersection stats %+v for %d items", stats, intersected.Len())
+	}
+
+	// lives as long as the longest lived
+	skipc, skipd := New(0.5, 30, FAST), New(0.5, 30, FAST)
+	skipc.InsertWithTTL(counter{1, 1}, time.Hour)
+	skipd.Insert(counter{1, 1})
+	merged, _ = New(0.5, 30, FAST).UnionWith(skipc, skipd, sum)
+	if merged.head.loadNext(0).expires != 0 {
+		t.Fatalf("resolved item expires")
+	}
+	merged, _ = New(0.5, 30, FAST).UnionWith(skipd, skipc, sum)
+	if merged.head.loadNext(0).expires != 0 {
+		t.Fatalf("resolved item expires")
+	}
+
+	// a resolver changing the order panics
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Errorf("resolving to an other key did not panic")
+			}
+		}()
+		New(0.5, 30, FAST).UnionWith(skipa, skipb, func(a, b SkiplistItem) SkiplistItem {
+			return counter{key: -1}
+		})
+	}()
+
+	fmt.Println("OK!")
+	fmt.Println("----------------------------------------")
+}
+
+func TestSubscribeSnapshotConsumer(t *testing.T) {
+	fmt.Println("---------------------------------------")
+	fmt.Println("Blocking subscriber taking snapshots")
+	fmt.Println("----------------------------------------")
+
+	var head = New(0.5, 30, FAST)
+	sub := head.Subscribe(nil, nil, 0, OverflowBlock)
+
+	// the consumer takes snapshots and clones while writers wait on it
+	consumed := make(chan int)
+	go func() {
+		count := 0
+		for range sub.Events() {
+			head.Snapshot().Release()
+			head.Clone()
+			count++
+		}
+		consumed <- count
+	}()
+
+	// removed items become tombstones while a snapshot is live
+	snap := head.Snapshot()
+
+	done := make(chan struct{})
+	go func() {
+		var wg sync.WaitGroup
+		wg.Add(nRoutinesToUse)
+		for index := 0; index < nRoutinesToUse; index++ {
+			go func(v int) {
+				defer wg.Done()
+				for i := 0; i < 100; i++ {
+					item := Int(v*100 + i)
+					head.Insert(item)
+					head.Remove(item)
+				}
+			}(index)
+		}
+		wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(30 * time.Second):
+		t.Fatalf("Writers deadlocked with a blocking subscriber")
+	}
+
+	snap.Release()
+	sub.Close()
+	if count := <-consumed; count != 2*100*nRoutinesToUse {
+		t.Errorf("Received %d events, should be %d", count, 2*100*nRoutinesToUse)
+	}
+
+	fmt.Println("OK!")
+	fmt.Println("----------------------------------------")
+}
+
+func BenchmarkInsert(b *testing.B) {
+	rand.Seed(time.Now().UTC().UnixNano())
+
+	var head = New(0.5, 30, FAST)
+
+	b.ResetTimer()
+
+	//var wg sync.WaitGroup
+
+	for index := 0; index < b.N; index++ {
+		if !head.Insert(Int(index)) {
+			b.Errorf("Could not insert item %d", index)
+		}
+	}
+
+}
+
+func BenchmarkInsertMany(b *testing.B) {
+	rand.Seed(time.Now().UTC().UnixNano())
+
+	var head = New(0.5, 30, FAST)
+
+	items := make([]SkiplistItem, b.N)
+	for index := range items {
+		items[index] = Int(index)
+	}
+
+	b.ResetTimer()
+
+	head.InsertMany(items)
+
+}
+
+func BenchmarkDelete(b *testing.B) {
+	rand.Seed(time.Now().UTC().UnixNano())
+
+	var head = New(0.5, 30, FAST)
+
+	for index := 0; index < b.N; index++ {
+		if !head.Insert(Int(index)) {
+			b.Errorf("Could not insert item %d", index)
+		}
+	}
+
+	b.ResetTimer()
+
+	for index := 0; index < b.N; index++ {
+		if !head.Remove(Int(index)) {
+			b.Errorf("Could not insert item %d", index)
+		}
+	}
+
+}
+
+func BenchmarkSearch(b *testing.B) {
+	rand.Seed(time.Now().UTC().UnixNano())
+
+	var head = New(0.5, 30, FAST)
+
+	for index := 0; index < b.N; index++ {
+		if !head.Insert(Int(index)) {
+			b.Errorf("Could not insert item %d", index)
+		}
+	}
+
+	b.ResetTimer()
+
+	for index := 0; index < b.N; index++ {
+		if !head.Contains(Int(index)) {
+			b.Errorf("Could not insert item %d", index)
+		}
+	}
+
+}
+
+func BenchmarkFromSorted(b *testing.B) {
+	rand.Seed(time.Now().UTC().UnixNano())
+
+	items := make([]SkiplistItem, b.N)
+	for index := range items {
+		items[index] = Int(index)
+	}
+
+	b.ResetTimer()
+
+	if _, err := New(0.5, 30, FAST).FromSorted(items); err != nil {
+		b.Errorf("Could not load sorted items: %v", err)
+	}
+
 }
 
 func BenchmarkUnion(b *testing.B) {
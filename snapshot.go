@@ -0,0 +1,366 @@
+package goskiplist
+
+import (
+	"sync/atomic"
+	"unsafe"
+)
+
+/*Snapshot : Frozen view of a Skiplist at the moment it was taken.
+Writers go on while the view stays the same, removed items are kept
+as tombstones until no snapshot can see them anymore.
+Release the snapshot when done. */
+type Snapshot struct {
+	list    *Skiplist
+	head    *skiplistNode
+	nLevels int
+	commit  int64
+	now     int64 // clock at creation, items expired by then are not seen
+}
+
+/*Snapshot : Take a consistent read only view of the Skiplist.
+Every change committed before the call is seen, none after it.
+Thread safe. */
+func (list *Skiplist) Snapshot() *Snapshot {
+	now := list.now()
+
+	list.lock.RLock()
+	head := list.head
+	nLevels := list.nLevels
+	list.lock.RUnlock()
+
+	// no writer is between taking its commit and applying it
+	list.snapMux.Lock()
+	snap := &Snapshot{
+		list:    list,
+		head:    head,
+		nLevels: nLevels,
+		commit:  atomic.LoadInt64(&list.commitClock),
+		now:     now,
+	}
+	list.snapshots = append(list.snapshots, snap)
+	list.snapMux.Unlock()
+
+	return snap
+}
+
+/*Release : Drop the snapshot and collect the versions only it could see.
+The snapshot must not be used afterwards. Thread safe. */
+func (snap *Snapshot) Release() {
+	list := snap.list
+
+	list.snapMux.Lock()
+	for index, other := range list.snapshots {
+		if other == snap {
+			list.snapshots = append(list.snapshots[:index], list.snapshots[index+1:]...)
+			break
+		}
+	}
+	list.snapMux.Unlock()
+
+	list.collect()
+}
+
+/*Contains : Return true if an item equal to val was in the Skiplist
+when the snapshot was taken. Thread safe. */
+func (snap *Snapshot) Contains(val SkiplistItem) bool {
+	return snap.Get(val) != nil
+}
+
+/*Get : Get the item equal to val as it was when the snapshot was taken,
+nil if there was none. Thread safe. */
+func (snap *Snapshot) Get(val SkiplistItem) SkiplistItem {
+
+	pred := snap.head
+	var curr *skiplistNode
+	// vertically
+	for level := snap.nLevels - 1; level >= 0; level-- {
+		// horizontally
+		curr = pred.loadNext(level)
+		for curr != nil && curr.value.Less(val) {
+			pred = curr
+			curr = pred.loadNext(level)
+		}
+	}
+
+	// a node being unlinked may be followed by a newer one
+	for ; curr != nil && curr.value.Equals(val); curr = curr.loadNext(0) {
+		if item := snap.visible(curr); item != nil {
+			return item
+		}
+	}
+	// not found
+	return nil
+}
+
+/*Iterator : Return an iterator over the items of the snapshot in ascending order.
+Thread safe. */
+func (snap *Snapshot) Iterator() *SnapshotIterator {
+	return &SnapshotIterator{snap: snap}
+}
+
+/*ToSortedArray : Return sorted array of the items of the snapshot. Thread safe. */
+func (snap *Snapshot) ToSortedArray() []SkiplistItem {
+	var arr []SkiplistItem
+	for it := snap.Iterator(); it.Next(); {
+		arr = append(arr, it.Item())
+	}
+	return arr
+}
+
+// visible item held by node when the snapshot was taken, nil if none
+func (snap *Snapshot) visible(node *skiplistNode) SkiplistItem {
+	item, _ := snap.visibleExpiry(node)
+	return item
+}
+
+// visibleExpiry visible item held by node and its expiration time
+func (snap *Snapshot) visibleExpiry(node *skiplistNode) (SkiplistItem, int64) {
+	// marked nodes were removed before the snapshot or were never seen by it
+	if !node.loadFullyLinked() || node.loadMarked() {
+		return nil, 0
+	}
+
+	version := node.loadVersion()
+	if version == nil {
+		// loaded in bulk, older than any snapshot of this head
+		expires := atomic.LoadInt64(&node.expires)
+		if expires != 0 && expires <= snap.now {
+			return nil, 0
+		}
+		return node.value, expires
+	}
+
+	for version != nil && !version.visibleAt(snap.commit) {
+		version = version.older
+	}
+	if version == nil || version.deleted || (version.expires != 0 && version.expires <= snap.now) {
+		return nil, 0
+	}
+	return version.value, version.expires
+}
+
+/*SnapshotIterator : Iterator over the items of a Snapshot in ascending order. */
+type SnapshotIterator struct {
+	snap *Snapshot
+	node *skiplistNode
+	item SkiplistItem
+}
+
+// Next advance to the next item, false when exhausted
+func (it *SnapshotIterator) Next() bool {
+	node := it.node
+	if node == nil {
+		node = it.snap.head
+	}
+
+	for node = node.loadNext(0); node != nil; node = node.loadNext(0) {
+		if item := it.snap.visible(node); item != nil {
+			it.node = node
+			it.item = item
+			return true
+		}
+	}
+
+	// exhausted, later items are newer than the snapshot
+	it.item = nil
+	return false
+}
+
+// Item current item, nil before the first call to Next
+// or once exhausted
+func (it *SnapshotIterator) Item() SkiplistItem {
+	return it.item
+}
+
+// item current item held by node
+func (node *skiplistNode) item() SkiplistItem {
+	if version := node.current(); version != nil {
+		return version.value
+	}
+	return node.value
+}
+
+// tombstoned true if the node was removed but is kept for snapshots
+func (node *skiplistNode) tombstoned() bool {
+	version := node.current()
+	return version != nil && version.deleted
+}
+
+// current newest committed version, nil if loaded in bulk
+func (node *skiplistNode) current() *nodeVersion {
+	version := node.loadVersion()
+	for version != nil && version.txn != nil && atomic.LoadInt64(&version.txn.commit) == 0 {
+		version = version.older
+	}
+	return version
+}
+
+// versioned give a node loaded in bulk a version to stack others on,
+// called with the node locked
+func (node *skiplistNode) versioned() {
+	if node.loadVersion() == nil {
+		node.storeVersion(&nodeVersion{value: node.value, expires: node.expires})
+	}
+}
+
+// loadVersion newest version, nil if loaded in bulk
+func (node *skiplistNode) loadVersion() *nodeVersion {
+	return (*nodeVersion)(atomic.LoadPointer(&node.version))
+}
+
+// storeVersion make version the newest,
+// called with the node locked or before it is linked
+func (node *skiplistNode) storeVersion(version *nodeVersion) {
+	atomic.StorePointer(&node.version, unsafe.Pointer(version))
+}
+
+// visibleAt true if the version was committed at or before commit
+func (version *nodeVersion) visibleAt(commit int64) bool {
+	if txn := version.txn; txn != nil {
+		ts := atomic.LoadInt64(&txn.commit)
+		return ts != 0 && ts <= commit
+	}
+	return version.commit <= commit
+}
+
+// commit next commit timestamp, called with snapMux read locked
+func (list *Skiplist) commit() int64 {
+	return atomic.AddInt64(&list.commitClock, 1)
+}
+
+// commitVersion make a locked node visible holding v and report it as op,
+// older versions are only kept if a snapshot may need them
+func (list *Skiplist) commitVersion(node *skiplistNode, v SkiplistItem, expires int64, op EventOp) {
+	list.snapMux.RLock()
+	older := node.loadVersion()
+	if len(list.snapshots) == 0 {
+		older = nil
+	}
+
+	node.storeVersion(&nodeVersion{value: v, commit: list.commit(), expires: expires, older: older})
+	atomic.StoreInt64(&node.expires, expires)
+	node.storeFullyLinked(true)
+	list.snapMux.RUnlock()
+
+	// queued under the node lock, the caller delivers it
+	list.publish(op, v)
+}
+
+// retire logically remove a locked live node. Returns true if it
+// must be unlinked, false if it stays linked as a tombstone because
+// a snapshot may still see it
+func (list *Skiplist) retire(node *skiplistNode) bool {
+	list.snapMux.RLock()
+	item := node.item()
+	node.versioned()
+
+	tombstone := list.seen(node)
+	if tombstone {
+		node.storeVersion(&nodeVersion{value: item, commit: list.commit(), deleted: true, older: node.loadVersion()})
+	} else {
+		node.storeMarked(true)
+	}
+	list.snapMux.RUnlock()
+
+	// queued under the node lock, the caller delivers it
+	list.publish(EventRemove, item)
+
+	return !tombstone
+}
+
+// seen true if a snapshot may see the node, which is the case
+// if one is newer than its oldest version. Called with snapMux read locked
+func (list *Skiplist) seen(node *skiplistNode) bool {
+	n := len(list.snapshots)
+	if n == 0 {
+		return false
+	}
+
+	oldest := node.loadVersion()
+	for oldest.older != nil {
+		oldest = oldest.older
+	}
+	return list.snapshots[n-1].commit >= oldest.commit
+}
+
+// collect drop the versions no snapshot can see anymore
+// and unlink tombstones older than every snapshot
+func (list *Skiplist) collect() {
+
+	// later snapshots see every commit up to here
+	list.snapMux.RLock()
+	horizon := atomic.LoadInt64(&list.commitClock)
+	if len(list.snapshots) > 0 {
+		horizon = list.snapshots[0].commit
+	}
+	list.snapMux.RUnlock()
+
+	for curr := list.head.loadNext(0); curr != nil; curr = curr.loadNext(0) {
+		// nodes being removed are left to their remover
+		// and nodes of a pending batch to the batch
+		if curr.loadMarked() || !curr.loadFullyLinked() || curr.loadOwner() != nil {
+			continue
+		}
+		if version := curr.loadVersion(); version == nil || (version.older == nil && !version.deleted) {
+			continue
+		}
+
+		unlink := false
+		curr.mux.Lock()
+		if curr.loadFullyLinked() && !curr.loadMarked() && curr.loadOwner() == nil {
+			// newest version seen by every snapshot
+			newest := curr.loadVersion()
+			keep := newest
+			for keep.older != nil && keep.commit > horizon {
+				keep = keep.older
+			}
+			if keep.older != nil {
+				curr.storeVersion(truncated(newest, keep))
+			}
+
+			if newest.deleted && newest.commit <= horizon {
+				curr.storeMarked(true)
+				unlink = true
+			}
+		}
+		curr.mux.Unlock()
+
+		// unlinked right away, a remover waiting for it
+		// may hold a lock needed further on
+		if unlink {
+			list.unlinkMarked([]*skiplistNode{curr})
+		}
+	}
+}
+
+// truncated copy of the versions from version down to keep,
+// which becomes the oldest. Readers go on with the original
+func truncated(version, keep *nodeVersion) *nodeVersion {
+	copied := *version
+	if version == keep {
+		copied.older = nil
+	} else {
+		copied.older = truncated(version.older, keep)
+	}
+	return &copied
+}
+
+// revive insert v into a tombstoned node, false if the node
+// was unlinked meanwhile and the insert has to start over
+func (list *Skiplist) revive(node *skiplistNode, v SkiplistItem, expires int64) bool {
+	node.mux.Lock()
+	if node.loadMarked() || node.loadOwner() != nil || !node.tombstoned() {
+		node.mux.Unlock()
+		return false
+	}
+	list.commitVersion(node, v, expires, EventInsert)
+	node.mux.Unlock()
+
+	list.lock.Lock()
+	list.nElements++
+	list.lock.Unlock()
+
+	list.enforceCapacity()
+
+	return true
+}
@@ -0,0 +1,145 @@
+package goskiplist
+
+import (
+	"fmt"
+	"sync/atomic"
+)
+
+/*Split : Cut the Skiplist at pivot by rewiring the links leaving the last
+node before pivot on each level, no node is copied.
+The Skiplist keeps the items less than pivot and is returned as left,
+right is a new Skiplist with the same parameters holding the rest.
+Relinking is O(log n) expected, counting the items of the smaller half
+is O(min(len(left), len(right))).
+Must not be called while snapshots of the Skiplist are live.
+Not threadsafe */
+func (list *Skiplist) Split(pivot SkiplistItem) (left, right *Skiplist) {
+
+	right = list.sibling()
+
+	var prev, next [SkiplistMaxLevel]*skiplistNode
+	list.Find(pivot, prev[:], next[:])
+
+	// nothing at or after pivot
+	if next[0] == nil {
+		return list, right
+	}
+
+	for level := 0; level < list.nLevels; level++ {
+		right.head.storeNext(level, next[level])
+		prev[level].storeNext(level, nil)
+	}
+	right.nLevels = list.nLevels
+
+	// backward links
+	next[0].storePrev(right.head)
+	right.storeTail(list.loadTail())
+	list.storeTail(prev[0])
+
+	list.shrink()
+	right.shrink()
+
+	// count the smaller half, walking both at once
+	a, b := list.head.loadNext(0), right.head.loadNext(0)
+	for a != nil && b != nil {
+		a, b = a.loadNext(0), b.loadNext(0)
+	}
+	if a == nil {
+		left := countItems(list.head.loadNext(0))
+		right.nElements = list.nElements - left
+		list.nElements = left
+	} else {
+		right.nElements = countItems(right.head.loadNext(0))
+		list.nElements -= right.nElements
+	}
+	list.moved()
+
+	return list, right
+}
+
+/*Join : Append the items of b to a, whose items must all be less than
+those of b, by linking the last node of a on each level to the first
+node of b, no node is copied. b is left empty.
+Returns a, or an error leaving both untouched if the items overlap.
+O(log n) expected. Must not be called while snapshots of a or b are live.
+Not threadsafe */
+func Join(a, b *Skiplist) (*Skiplist, error) {
+
+	first := b.head.loadNext(0)
+	if first == nil {
+		return a, nil
+	}
+	if a.loadTail() != a.head && !a.loadTail().value.Less(first.value) {
+		return a, fmt.Errorf("join: item %v is not less than item %v", a.loadTail().value, first.value)
+	}
+
+	a.maxLevels = max(a.maxLevels, b.nLevels)
+	a.nLevels = max(a.nLevels, b.nLevels)
+
+	// last node of a on each level, descending from the top
+	pred := a.head
+	for level := a.nLevels - 1; level >= 0; level-- {
+		for pred.loadNext(level) != nil {
+			pred = pred.loadNext(level)
+		}
+		pred.storeNext(level, b.head.loadNext(level))
+	}
+
+	// backward links
+	first.storePrev(a.loadTail())
+	a.storeTail(b.loadTail())
+	a.nElements += b.nElements
+
+	// left empty
+	b.head = new(skiplistNode)
+	b.head.storeFullyLinked(true)
+	b.storeTail(b.head)
+	b.nLevels = 1
+	b.nElements = 0
+	a.moved()
+	b.moved()
+
+	return a, nil
+}
+
+// sibling empty Skiplist with the same parameters, expiration and
+// capacity settings, subscriptions are not carried over
+func (list *Skiplist) sibling() *Skiplist {
+	defer list.lock.RUnlock()
+	list.lock.RLock()
+
+	other := New(list.prob, list.maxLevels, list.fastRandom)
+	other.clock = list.clock
+	other.ttl = list.ttl
+	other.onExpire = list.onExpire
+	other.maxElements = list.maxElements
+	other.maxBytes = list.maxBytes
+	other.evict = list.evict
+	other.onEvict = list.onEvict
+
+	return other
+}
+
+// shrink lower nLevels to the highest level still holding a node
+func (list *Skiplist) shrink() {
+	for list.nLevels > 1 && list.head.loadNext(list.nLevels-1) == nil {
+		list.nLevels--
+	}
+}
+
+// moved start a new generation, sending fingers back to the head
+// as the nodes they hold may now belong to an other list
+func (list *Skiplist) moved() {
+	atomic.AddInt64(&list.generation, 1)
+}
+
+// countItems count the items from node on, tombstones excluded
+func countItems(node *skiplistNode) int {
+	count := 0
+	for ; node != nil; node = node.loadNext(0) {
+		if !node.tombstoned() {
+			count++
+		}
+	}
+	return count
+}
@@ -0,0 +1,135 @@
+package goskiplist
+
+import (
+	"math"
+	"unsafe"
+)
+
+// statsSampleSize maximum amount of items searched
+// to sample the search path length
+const statsSampleSize = 1000
+
+/*ShapeStats : Shape of a Skiplist as reported by Stats */
+type ShapeStats struct {
+	// Elements linked on the lowest level
+	Elements int
+	// Levels in use
+	Levels int
+	// LevelCounts[i] is the amount of nodes linked on level i
+	LevelCounts []int
+
+	// ConfiguredProb probability the list generates levels with,
+	// always 0.5 in FAST mode
+	ConfiguredProb float64
+	// EmpiricalProb fraction of nodes of a level which also reach the next one
+	EmpiricalProb float64
+
+	// ExpectedSearchPath expected search path length for the configured
+	// probability and the current amount of elements
+	ExpectedSearchPath float64
+	// SampledSearchPath average search path length over sampled items
+	SampledSearchPath float64
+
+	// UsedPointers next pointer slots on levels a node is linked on
+	UsedPointers int
+	// WastedPointers next pointer slots allocated but never used
+	WastedPointers int
+	// EstimatedBytes estimated footprint of the list structure,
+	// excluding the memory referenced by the items
+	EstimatedBytes uintptr
+}
+
+/*Stats : Report the shape of the Skiplist: amount of nodes per level,
+empirical against configured probability, expected against sampled
+search path length, pointer slot usage and an estimated memory footprint.
+Figures are approximate when writers are active. */
+func (list *Skiplist) Stats() ShapeStats {
+
+	list.lock.RLock()
+	prob := list.prob
+	if list.fastRandom {
+		prob = 0.5
+	}
+	list.lock.RUnlock()
+
+	stats := ShapeStats{ConfiguredProb: prob}
+	counts := make([]int, SkiplistMaxLevel)
+
+	for curr := list.head.loadNext(0); curr != nil; curr = curr.loadNext(0) {
+		stats.Elements++
+		for level := 0; level <= curr.topLevel; level++ {
+			counts[level]++
+		}
+	}
+
+	for stats.Levels < SkiplistMaxLevel && counts[stats.Levels] > 0 {
+		stats.Levels++
+	}
+	stats.LevelCounts = counts[:stats.Levels]
+
+	// nodes which had the chance to be promoted
+	// against nodes which were promoted
+	trials, promoted := 0, 0
+	for level := 1; level < stats.Levels; level++ {
+		trials += counts[level-1]
+		promoted += counts[level]
+	}
+	if trials > 0 {
+		stats.EmpiricalProb = float64(promoted) / float64(trials)
+	}
+
+	// head uses one slot per level
+	stats.UsedPointers = stats.Levels
+	for _, count := range stats.LevelCounts {
+		stats.UsedPointers += count
+	}
+	// every node, head included, allocates SkiplistMaxLevel slots
+	stats.WastedPointers = (stats.Elements+1)*SkiplistMaxLevel - stats.UsedPointers
+
+	stats.EstimatedBytes = estimatedBytes(stats.Elements)
+
+	stats.ExpectedSearchPath = expectedSearchPath(stats.Elements, prob)
+	stats.SampledSearchPath = list.sampleSearchPath(stats.Elements)
+
+	return stats
+}
+
+// estimatedBytes footprint of a list of n elements,
+// excluding the memory referenced by the items
+func estimatedBytes(n int) uintptr {
+	return unsafe.Sizeof(Skiplist{}) + uintptr(n+1)*unsafe.Sizeof(skiplistNode{})
+}
+
+// expectedSearchPath Pugh's bound on the search path length
+// for n elements and probability p: log_{1/p}(n)/p + 1/(1-p)
+func expectedSearchPath(n int, p float64) float64 {
+	if n < 1 || p <= 0 || p >= 1 {
+		return 0
+	}
+	return math.Log(float64(n))/math.Log(1/p)/p + 1/(1-p)
+}
+
+// average search path length over up to statsSampleSize
+// items, evenly spread over the list
+func (list *Skiplist) sampleSearchPath(n int) float64 {
+	if n == 0 {
+		return 0
+	}
+
+	stride := max(1, n/statsSampleSize)
+	steps, samples := 0, 0
+
+	index := 0
+	for curr := list.head.loadNext(0); curr != nil; curr = curr.loadNext(0) {
+		if index%stride == 0 {
+			steps += list.Trace(curr.value).PathLength()
+			samples++
+		}
+		index++
+	}
+
+	if samples == 0 {
+		return 0
+	}
+	return float64(steps) / float64(samples)
+}
@@ -0,0 +1,298 @@
+package goskiplist
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+/*EventOp : Kind of change reported to subscribers */
+type EventOp int
+
+const (
+	// EventInsert an item was inserted
+	EventInsert EventOp = iota
+	// EventRemove an item was removed, expired or evicted
+	EventRemove
+	// EventUpdate an item was replaced by an equal one
+	EventUpdate
+)
+
+func (op EventOp) String() string {
+	switch op {
+	case EventInsert:
+		return "insert"
+	case EventRemove:
+		return "remove"
+	case EventUpdate:
+		return "update"
+	}
+	return "unknown"
+}
+
+/*Event : Change of a single item */
+type Event struct {
+	Op   EventOp
+	Item SkiplistItem
+}
+
+/*OverflowPolicy : What happens to events for a subscriber whose buffer is full */
+type OverflowPolicy int
+
+const (
+	// OverflowDrop drop the event and count it
+	OverflowDrop OverflowPolicy = iota
+	// OverflowBlock block the writer until the subscriber catches up.
+	// The writer blocks once its locks are released, but later writers
+	// queue behind it, so the consumer must not wait on writers of the
+	// list, such as by inserting to it, before receiving the next event
+	OverflowBlock
+	// OverflowCoalesce queue the event, keeping only the latest
+	// pending event of every item
+	OverflowCoalesce
+)
+
+/*Subscription : Stream of the changes to a range of a Skiplist.
+Events of the same item are delivered in the order they happened. */
+type Subscription struct {
+	list   *Skiplist
+	lo, hi SkiplistItem
+	policy OverflowPolicy
+
+	events  chan Event
+	done    chan struct{}
+	dropped int64
+	sendMux sync.Mutex // held while delivering, events is not closed meanwhile
+
+	// coalescing
+	mux      sync.Mutex
+	pending  []Event
+	flushing bool
+	notify   chan struct{}
+	flusher  sync.WaitGroup
+
+	closeOnce sync.Once
+}
+
+/*Subscribe : Subscribe to inserts and removes of items x with lo <= x < hi,
+a nil lo or hi leaves that side of the range open.
+Events are taken at the point the change takes effect, delivered once the
+writer released its locks and buffered up to buffer events,
+policy decides what happens once the buffer is full.
+Close the subscription when done. Thread safe. */
+func (list *Skiplist) Subscribe(lo, hi SkiplistItem, buffer int, policy OverflowPolicy) *Subscription {
+
+	sub := &Subscription{
+		list:   list,
+		lo:     lo,
+		hi:     hi,
+		policy: policy,
+		events: make(chan Event, max(0, buffer)),
+		done:   make(chan struct{}),
+	}
+
+	if policy == OverflowCoalesce {
+		sub.notify = make(chan struct{}, 1)
+		sub.flusher.Add(1)
+		go sub.flush()
+	}
+
+	list.subMux.Lock()
+	list.subs = append(list.subs, sub)
+	atomic.AddInt32(&list.nSubs, 1)
+	list.subMux.Unlock()
+
+	return sub
+}
+
+// Events channel of the subscription, closed by Close
+func (sub *Subscription) Events() <-chan Event {
+	return sub.events
+}
+
+// Dropped amount of events dropped because the buffer was full
+func (sub *Subscription) Dropped() int {
+	return int(atomic.LoadInt64(&sub.dropped))
+}
+
+// Close stop receiving events and close the events channel,
+// pending coalesced events are discarded
+func (sub *Subscription) Close() {
+	sub.closeOnce.Do(func() {
+		// release writers blocked on this subscription
+		close(sub.done)
+
+		list := sub.list
+		list.subMux.Lock()
+		for index, other := range list.subs {
+			if other == sub {
+				list.subs = append(list.subs[:index], list.subs[index+1:]...)
+				atomic.AddInt32(&list.nSubs, -1)
+				break
+			}
+		}
+		list.subMux.Unlock()
+
+		// no writer can deliver anymore
+		sub.sendMux.Lock()
+		sub.sendMux.Unlock()
+		sub.flusher.Wait()
+		close(sub.events)
+	})
+}
+
+// queuedEvent published event and the subscribers it is for
+type queuedEvent struct {
+	event Event
+	subs  []*Subscription
+}
+
+// publish queue an event for every subscriber of the item range,
+// called at the linearization point of the change. Nothing blocks here,
+// the writer calls dispatch once it released its locks
+func (list *Skiplist) publish(op EventOp, item SkiplistItem) {
+	if atomic.LoadInt32(&list.nSubs) == 0 {
+		return
+	}
+
+	var subs []*Subscription
+	list.subMux.RLock()
+	for _, sub := range list.subs {
+		if sub.contains(item) {
+			subs = append(subs, sub)
+		}
+	}
+	list.subMux.RUnlock()
+
+	if len(subs) == 0 {
+		return
+	}
+
+	list.eventMux.Lock()
+	list.events = append(list.events, queuedEvent{event: Event{Op: op, Item: item}, subs: subs})
+	atomic.AddInt32(&list.nEvents, 1)
+	list.eventMux.Unlock()
+}
+
+// dispatch deliver the queued events in the order they were published,
+// called by writers holding no lock. One routine delivers at a time,
+// returns once the events queued before the call are delivered
+func (list *Skiplist) dispatch() {
+	if atomic.LoadInt32(&list.nEvents) == 0 {
+		return
+	}
+
+	defer list.dispatchMux.Unlock()
+	list.dispatchMux.Lock()
+
+	for {
+		list.eventMux.Lock()
+		if len(list.events) == 0 {
+			list.eventMux.Unlock()
+			return
+		}
+		queued := list.events[0]
+		list.events[0] = queuedEvent{}
+		list.events = list.events[1:]
+		list.eventMux.Unlock()
+
+		for _, sub := range queued.subs {
+			sub.deliver(queued.event)
+		}
+		atomic.AddInt32(&list.nEvents, -1)
+	}
+}
+
+// contains true if item is in the subscribed range
+func (sub *Subscription) contains(item SkiplistItem) bool {
+	return (sub.lo == nil || !item.Less(sub.lo)) && (sub.hi == nil || item.Less(sub.hi))
+}
+
+// deliver hand the event over according to the overflow policy
+func (sub *Subscription) deliver(event Event) {
+	defer sub.sendMux.Unlock()
+	sub.sendMux.Lock()
+
+	// closed meanwhile
+	select {
+	case <-sub.done:
+		return
+	default:
+	}
+
+	switch sub.policy {
+	case OverflowBlock:
+		select {
+		case sub.events <- event:
+		case <-sub.done:
+		}
+
+	case OverflowCoalesce:
+		sub.mux.Lock()
+		defer sub.mux.Unlock()
+
+		// nothing queued, events can not overtake older ones
+		if len(sub.pending) == 0 && !sub.flushing {
+			select {
+			case sub.events <- event:
+				return
+			default:
+			}
+		}
+
+		// keep only the latest event of the item
+		for index := range sub.pending {
+			if sub.pending[index].Item.Equals(event.Item) {
+				sub.pending[index] = event
+				return
+			}
+		}
+		sub.pending = append(sub.pending, event)
+
+		select {
+		case sub.notify <- struct{}{}:
+		default:
+		}
+
+	default:
+		select {
+		case sub.events <- event:
+		default:
+			atomic.AddInt64(&sub.dropped, 1)
+		}
+	}
+}
+
+// flush deliver queued events of a coalescing subscription in order
+func (sub *Subscription) flush() {
+	defer sub.flusher.Done()
+
+	for {
+		select {
+		case <-sub.notify:
+		case <-sub.done:
+			return
+		}
+
+		for {
+			sub.mux.Lock()
+			if len(sub.pending) == 0 {
+				sub.mux.Unlock()
+				break
+			}
+			event := sub.pending[0]
+			sub.pending = sub.pending[1:]
+			sub.flushing = true
+			sub.mux.Unlock()
+
+			select {
+			case sub.events <- event:
+			case <-sub.done:
+				return
+			}
+
+			sub.mux.Lock()
+			sub.flushing = false
+			sub.mux.Unlock()
+		}
+	}
+}
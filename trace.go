@@ -0,0 +1,105 @@
+package goskiplist
+
+/*TraceAction : What a search did at a single step */
+type TraceAction int
+
+const (
+	// TraceAdvance the item was less than the searched value,
+	// the search moved forward to it
+	TraceAdvance TraceAction = iota
+	// TraceFound the item equals the searched value
+	TraceFound
+	// TraceDrop the item was not less than the searched value
+	// or the end of the level was reached, the search dropped a level
+	TraceDrop
+)
+
+func (action TraceAction) String() string {
+	switch action {
+	case TraceAdvance:
+		return "advance"
+	case TraceFound:
+		return "found"
+	case TraceDrop:
+		return "drop"
+	}
+	return "unknown"
+}
+
+/*TraceStep : A single step of a traced search */
+type TraceStep struct {
+	// Level the step was taken on
+	Level int
+	// Item compared against the searched value,
+	// nil when the end of the level was reached
+	Item SkiplistItem
+	// Action taken after the comparison
+	Action TraceAction
+}
+
+/*SearchTrace : Path followed by a search and the comparisons it made */
+type SearchTrace struct {
+	Steps []TraceStep
+	// FoundLevel highest level the value was found on, -1 when not found
+	FoundLevel int
+	// LessCalls calls to Less
+	LessCalls int
+	// EqualsCalls calls to Equals
+	EqualsCalls int
+	// Advances forward moves
+	Advances int
+	// LevelDrops levels dropped
+	LevelDrops int
+}
+
+/*Trace : Search for val the way Find does and return every step taken
+along with the amount of comparisons made. Threadsafe. */
+func (list *Skiplist) Trace(val SkiplistItem) *SearchTrace {
+	var prev, next [SkiplistMaxLevel]*skiplistNode
+
+	trace := new(SearchTrace)
+	trace.FoundLevel = list.find(val, prev[:], next[:], trace)
+
+	return trace
+}
+
+// PathLength forward moves and level drops taken by the search
+func (trace *SearchTrace) PathLength() int {
+	return trace.Advances + trace.LevelDrops
+}
+
+/* helpers called by find, no-ops on a nil trace
+apart from the comparison itself */
+
+func (trace *SearchTrace) less(a, b SkiplistItem) bool {
+	if trace != nil {
+		trace.LessCalls++
+	}
+	return a.Less(b)
+}
+
+func (trace *SearchTrace) equals(a, b SkiplistItem) bool {
+	if trace != nil {
+		trace.EqualsCalls++
+	}
+	return a.Equals(b)
+}
+
+func (trace *SearchTrace) step(level int, node *skiplistNode, action TraceAction) {
+	if trace == nil {
+		return
+	}
+
+	var item SkiplistItem
+	if node != nil {
+		item = node.value
+	}
+	trace.Steps = append(trace.Steps, TraceStep{Level: level, Item: item, Action: action})
+
+	switch action {
+	case TraceAdvance:
+		trace.Advances++
+	case TraceDrop:
+		trace.LevelDrops++
+	}
+}
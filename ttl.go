@@ -0,0 +1,173 @@
+package goskiplist
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+)
+
+/*Clock : Source of the current time for expiration,
+replace it with SetClock for deterministic tests. */
+type Clock interface {
+	Now() time.Time
+}
+
+// systemClock wall clock, used when no clock is set
+type systemClock struct{}
+
+func (systemClock) Now() time.Time {
+	return time.Now()
+}
+
+/*SetClock : Set the clock used to expire items, nil restores the wall clock.
+Threadsafe */
+func (list *Skiplist) SetClock(clock Clock) {
+	defer list.lock.Unlock()
+	list.lock.Lock()
+	list.clock = clock
+}
+
+/*SetDefaultTTL : Set the time to live of items inserted from now on
+by Insert and InsertMany, ttl <= 0 means items never expire.
+Threadsafe */
+func (list *Skiplist) SetDefaultTTL(ttl time.Duration) {
+	defer list.lock.Unlock()
+	list.lock.Lock()
+	list.ttl = ttl
+}
+
+/*OnExpire : Set a callback called with every expired item once it is
+physically removed, nil removes the callback.
+Threadsafe */
+func (list *Skiplist) OnExpire(callback func(SkiplistItem)) {
+	defer list.lock.Unlock()
+	list.lock.Lock()
+	list.onExpire = callback
+}
+
+/*InsertWithTTL : Insert node with value v to Skiplist, expiring after ttl instead
+of the default time to live. ttl <= 0 means the item never expires.
+An expired item is invisible to Contains, Get and iteration, and can be inserted again.
+Returns true on success,false on failure to insert. Thread safe. */
+func (list *Skiplist) InsertWithTTL(v SkiplistItem, ttl time.Duration) bool {
+	// buffers to store prev and next pointers
+	var prev, next [SkiplistMaxLevel]*skiplistNode
+
+	return list.insert(context.Background(), v, prev[:], next[:], false, list.expiryAfter(ttl)) == nil
+}
+
+/*Reap : Physically remove every expired item, calling the expiry callback
+for each. Returns the amount of items removed. Thread safe. */
+func (list *Skiplist) Reap() int {
+	victims := list.removeNodes(nil, nil, list.expired)
+	list.notifyExpired(victims)
+	return len(victims)
+}
+
+/*StartReaper : Start a background routine calling Reap every interval,
+replacing any reaper already running. Thread safe. */
+func (list *Skiplist) StartReaper(interval time.Duration) {
+	list.StopReaper()
+
+	stop := make(chan struct{})
+
+	list.lock.Lock()
+	list.stopReaper = stop
+	list.lock.Unlock()
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				list.Reap()
+			case <-stop:
+				return
+			}
+		}
+	}()
+}
+
+/*StopReaper : Stop the background reaper, if running. Thread safe. */
+func (list *Skiplist) StopReaper() {
+	defer list.lock.Unlock()
+	list.lock.Lock()
+
+	if list.stopReaper != nil {
+		close(list.stopReaper)
+		list.stopReaper = nil
+	}
+}
+
+// now current time of the list clock in nanoseconds
+func (list *Skiplist) now() int64 {
+	list.lock.RLock()
+	clock := list.clock
+	list.lock.RUnlock()
+
+	if clock == nil {
+		clock = systemClock{}
+	}
+	return clock.Now().UnixNano()
+}
+
+// defaultExpiry expiration time for items inserted now with the default ttl
+func (list *Skiplist) defaultExpiry() int64 {
+	list.lock.RLock()
+	ttl := list.ttl
+	list.lock.RUnlock()
+
+	return list.expiryAfter(ttl)
+}
+
+// expiryAfter expiration time for items inserted now with ttl,
+// zero if they never expire
+func (list *Skiplist) expiryAfter(ttl time.Duration) int64 {
+	if ttl <= 0 {
+		return 0
+	}
+	return list.now() + int64(ttl)
+}
+
+// expired true if the node has a time to live which passed
+func (list *Skiplist) expired(node *skiplistNode) bool {
+	expires := atomic.LoadInt64(&node.expires)
+	if version := node.current(); version != nil {
+		expires = version.expires
+	}
+	return expires != 0 && list.now() >= expires
+}
+
+// isLive true if the node is fully inserted, not removed and not expired
+func (list *Skiplist) isLive(node *skiplistNode) bool {
+	return node.loadFullyLinked() && !node.loadMarked() && !node.tombstoned() && !list.expired(node)
+}
+
+// expire physically remove an expired node found by some operation,
+// false if some other routine removed it first
+func (list *Skiplist) expire(node *skiplistNode) bool {
+	claimed, unlink := list.claim(node)
+	if !claimed {
+		return false
+	}
+
+	list.popClaimed(node, unlink)
+	list.notifyExpired([]*skiplistNode{node})
+	return true
+}
+
+// notifyExpired call the expiry callback for every removed node
+func (list *Skiplist) notifyExpired(nodes []*skiplistNode) {
+	list.lock.RLock()
+	callback := list.onExpire
+	list.lock.RUnlock()
+
+	if callback == nil {
+		return
+	}
+	for _, node := range nodes {
+		callback(node.item())
+	}
+}
@@ -0,0 +1,134 @@
+package goskiplist
+
+import (
+	"fmt"
+)
+
+/*Validate : Check the structural invariants of the Skiplist.
+
+Every level must be sorted and be a subsequence of the level below,
+every node must be linked on exactly the levels 0..topLevel,
+backward links and tail must mirror the lowest level,
+no marked or partially linked node may be reachable, the head must be
+well-formed and nLevels and nElements must agree with the nodes
+actually linked, not counting tombstones kept for snapshots.
+
+Returns nil if the structure is sound, else an error describing the
+first violation found.
+Not threadsafe, call when no writers are active. */
+func (list *Skiplist) Validate() error {
+
+	head := list.head
+	if head == nil {
+		return fmt.Errorf("validate: head node is nil")
+	}
+	if head.value != nil {
+		return fmt.Errorf("validate: head node holds value %v", head.value)
+	}
+	if !head.loadFullyLinked() || head.loadMarked() {
+		return fmt.Errorf("validate: head node is marked or not fully linked")
+	}
+
+	if list.nLevels < 0 || list.nLevels > SkiplistMaxLevel {
+		return fmt.Errorf("validate: nLevels %d out of range [0, %d]", list.nLevels, SkiplistMaxLevel)
+	}
+
+	// position of every node on the lowest level
+	position := make(map[*skiplistNode]int, list.nElements)
+	// amount of levels every node was found in
+	linkedLevels := make(map[*skiplistNode]int, list.nElements)
+
+	// lowest level holds every element
+	var prev *skiplistNode
+	counter := 0
+	for curr := head.loadNext(0); curr != nil; curr = curr.loadNext(0) {
+		if err := validateNode(curr, prev, 0); err != nil {
+			return err
+		}
+		if _, seen := position[curr]; seen {
+			return fmt.Errorf("validate: cycle at level 0 on item %v", curr.value)
+		}
+		if (prev == nil && curr.loadPrev() != head) || (prev != nil && curr.loadPrev() != prev) {
+			return fmt.Errorf("validate: item %v has a wrong backward link", curr.value)
+		}
+		position[curr] = len(position)
+		linkedLevels[curr] = 1
+		// tombstones kept for snapshots are not counted
+		if !curr.tombstoned() {
+			counter++
+		}
+		prev = curr
+	}
+
+	if (prev == nil && list.loadTail() != head) || (prev != nil && list.loadTail() != prev) {
+		return fmt.Errorf("validate: tail does not point to the last item")
+	}
+
+	if counter != list.nElements {
+		return fmt.Errorf("validate: nElements is %d but %d items are linked", list.nElements, counter)
+	}
+
+	// every upper level must be a sorted subsequence of the lowest
+	highest := 0
+	for level := 1; level < SkiplistMaxLevel; level++ {
+		prev = nil
+		lastPosition := -1
+		for curr := head.loadNext(level); curr != nil; curr = curr.loadNext(level) {
+			if err := validateNode(curr, prev, level); err != nil {
+				return err
+			}
+			pos, ok := position[curr]
+			if !ok {
+				return fmt.Errorf("validate: item %v linked at level %d but not at level 0", curr.value, level)
+			}
+			if pos <= lastPosition {
+				return fmt.Errorf("validate: level %d is not a subsequence of level 0 at item %v", level, curr.value)
+			}
+			if linkedLevels[curr] != level {
+				return fmt.Errorf("validate: item %v linked at level %d but missing from level %d", curr.value, level, level-1)
+			}
+			linkedLevels[curr]++
+			lastPosition = pos
+			prev = curr
+			highest = level
+		}
+	}
+
+	if len(position) > 0 && highest >= list.nLevels {
+		return fmt.Errorf("validate: nLevels is %d but level %d is in use", list.nLevels, highest)
+	}
+
+	// topLevel must match the links of every node
+	for node, levels := range linkedLevels {
+		if node.topLevel != levels-1 {
+			return fmt.Errorf("validate: item %v has topLevel %d but is linked on %d levels", node.value, node.topLevel, levels)
+		}
+		for level := levels; level < SkiplistMaxLevel; level++ {
+			if node.loadNext(level) != nil {
+				return fmt.Errorf("validate: item %v has a link at level %d above its topLevel %d", node.value, level, node.topLevel)
+			}
+		}
+	}
+
+	return nil
+}
+
+// checks a single reachable node against its predecessor on the same level
+func validateNode(curr, prev *skiplistNode, level int) error {
+	if curr.loadMarked() {
+		return fmt.Errorf("validate: marked item %v reachable at level %d", curr.value, level)
+	}
+	if !curr.loadFullyLinked() {
+		return fmt.Errorf("validate: item %v reachable at level %d but not fully linked", curr.value, level)
+	}
+	if curr.value == nil {
+		return fmt.Errorf("validate: nil item reachable at level %d", level)
+	}
+	if curr.topLevel < level {
+		return fmt.Errorf("validate: item %v reachable at level %d above its topLevel %d", curr.value, level, curr.topLevel)
+	}
+	if prev != nil && !prev.value.Less(curr.value) {
+		return fmt.Errorf("validate: level %d out of order, %v is followed by %v", level, prev.value, curr.value)
+	}
+	return nil
+}